@@ -0,0 +1,581 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PeterHackz/conv3d/models"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+func decodeCommand(fs *flag.FlagSet) func(args []string) error {
+	var load loadFlags
+	load.register(fs)
+	output := fs.String("o", "", "output file (default: the input file with a .scw.json extension)")
+	version := outVersionFlag(fs)
+
+	return func(args []string) error {
+		if err := exactArgs(args, 1); err != nil {
+			return err
+		}
+
+		model, _, err := load.load(args[0])
+		if err != nil {
+			return err
+		}
+
+		format := models.Lookup("scw.json")
+		if *output, err = outputFile(*output, args[0], format); err != nil {
+			return err
+		}
+
+		if err = setOutVersion(model, format, *version); err != nil {
+			return err
+		}
+
+		return models.WriteFile(*output, format.Name, model)
+	}
+}
+
+func encodeCommand(fs *flag.FlagSet) func(args []string) error {
+	var load loadFlags
+	load.register(fs)
+	output := fs.String("o", "", "output file (default: the input file with a .scw extension)")
+	version := outVersionFlag(fs)
+	quantize := fs.Bool("quantize", false, "compute the smallest scale that fits the vertex data of every source array")
+	report := fs.Bool("quantization-report", false, "print the scale and max quantization error of every source array")
+
+	return func(args []string) error {
+		if err := exactArgs(args, 1); err != nil {
+			return err
+		}
+
+		model, _, err := load.load(args[0])
+		if err != nil {
+			return err
+		}
+
+		format := models.Lookup("scw")
+		if *output, err = outputFile(*output, args[0], format); err != nil {
+			return err
+		}
+
+		if err = setOutVersion(model, format, *version); err != nil {
+			return err
+		}
+
+		if *quantize {
+			model.Requantize()
+		}
+
+		if *report {
+			quantizationReport(model)
+		}
+
+		return models.WriteFile(*output, format.Name, model)
+	}
+}
+
+func convertCommand(fs *flag.FlagSet) func(args []string) error {
+	var load loadFlags
+	load.register(fs)
+	output := fs.String("o", "", "output file (default: the input file with the extension of the format)")
+	to := fs.String("to", "", "output format (see conv3d formats), guessed from the -o extension if not set")
+	version := outVersionFlag(fs)
+
+	return func(args []string) error {
+		if err := exactArgs(args, 1); err != nil {
+			return err
+		}
+
+		var (
+			format *models.Format
+			err    error
+		)
+
+		switch {
+		case len(*to) != 0:
+			if format = models.Lookup(*to); format == nil {
+				return fmt.Errorf("%w: %s", models.ErrUnknownFormat, *to)
+			}
+		case len(*output) != 0:
+			if format, err = models.ByExtension(*output); err != nil {
+				return err
+			}
+		default:
+			return usagef("expected an output format, set it with --to")
+		}
+
+		if !format.CanWrite() {
+			return fmt.Errorf("%w: %s", models.ErrNotWritable, format.Name)
+		}
+
+		if *output, err = outputFile(*output, args[0], format); err != nil {
+			return err
+		}
+
+		// scw outputs keep the whole File (unknown chunks, raw nodes and samples),
+		// every other pair of formats is converted through the format neutral scene
+		if format.Name != "scw" && format.Name != "scw.json" {
+			s, _, err := load.loadScene(args[0])
+			if err != nil {
+				return err
+			}
+			return models.WriteScene(*output, format.Name, s)
+		}
+
+		model, _, err := load.load(args[0])
+		if err != nil {
+			return err
+		}
+
+		if err = setOutVersion(model, format, *version); err != nil {
+			return err
+		}
+
+		return models.WriteFile(*output, format.Name, model)
+	}
+}
+
+// outVersionFlag registers the scw version of the files written by decode, encode and convert
+func outVersionFlag(fs *flag.FlagSet) *int {
+	return fs.Int("out-version", scw.LatestVersion, fmt.Sprintf("scw version of scw and scw JSON outputs (0 to %d)", scw.LatestVersion))
+}
+
+// setOutVersion migrates model to version when it is written to an scw format, the other formats ignore it
+func setOutVersion(model *scw.File, format *models.Format, version int) error {
+	if format.Name != "scw" && format.Name != "scw.json" {
+		return nil
+	}
+
+	if version < 0 || version > scw.LatestVersion {
+		return fmt.Errorf("%w: %d", scw.ErrUnsupportedVersion, version)
+	}
+
+	_, err := scw.Migrate(model, uint16(version), scw.MinorVersionAuto)
+	return err
+}
+
+// modelInfo is what info prints, the counts are kept apart from file as streamed scw files
+// do not decode the chunks info only counts
+type modelInfo struct {
+	format string
+	// file has the header, geometries and nodes
+	file *scw.File
+
+	materials, cameras  int
+	unknown, chunkOrder []string
+}
+
+func fileInfo(file *scw.File, format *models.Format) *modelInfo {
+	info := &modelInfo{
+		format:     format.Name,
+		file:       file,
+		materials:  len(file.Materials),
+		cameras:    len(file.Cameras),
+		chunkOrder: file.ChunkOrder,
+	}
+
+	for _, chunk := range file.UnknownChunks {
+		info.unknown = append(info.unknown, chunk.Tag)
+	}
+	return info
+}
+
+// streamInfo reads an scw file one chunk at a time, only HEAD, GEOM and NODE chunks are decoded
+func streamInfo(r io.Reader, load *loadFlags) (*modelInfo, error) {
+	decoder := scw.NewDecoder(r)
+	decoder.VerifyCRC = load.strict
+	if load.minorVersion != scw.MinorVersionAuto {
+		decoder.File().MinorVersion = load.minorVersion
+	}
+
+	info := &modelInfo{format: "scw", file: decoder.File()}
+
+	for {
+		chunk, err := decoder.Next()
+		if err == io.EOF {
+			return info, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if chunk.Tag != "WEND" {
+			info.chunkOrder = append(info.chunkOrder, chunk.Tag)
+		}
+
+		switch chunk.Tag {
+		case "HEAD", "GEOM", "NODE":
+			_, err = decoder.Decode()
+		case "MATE":
+			info.materials++
+			err = decoder.Skip()
+		case "CAME":
+			info.cameras++
+			err = decoder.Skip()
+		case "WEND":
+			err = decoder.Skip()
+		default:
+			info.unknown = append(info.unknown, chunk.Tag)
+			err = decoder.Skip()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// loadInfo streams scw files, the other formats are loaded
+func loadInfo(filename string, load *loadFlags) (*modelInfo, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buffered := bufio.NewReader(file)
+	if head, _ := buffered.Peek(len(scw.Magic)); scw.IsSCW(head) {
+		return streamInfo(buffered, load)
+	}
+
+	model, format, err := load.load(filename)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo(model, format), nil
+}
+
+func infoCommand(fs *flag.FlagSet) func(args []string) error {
+	var load loadFlags
+	load.register(fs)
+
+	return func(args []string) error {
+		if err := exactArgs(args, 1); err != nil {
+			return err
+		}
+
+		info, err := loadInfo(args[0], &load)
+		if err != nil {
+			return err
+		}
+		model := info.file
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintf(w, "format:\t%s\n", info.format)
+		if model.Version == 0 {
+			fmt.Fprintf(w, "version:\t%d (minor %d)\n", model.Version, model.MinorVersion)
+		} else {
+			fmt.Fprintf(w, "version:\t%d\n", model.Version)
+		}
+		fmt.Fprintf(w, "frames:\t%d-%d at %d fps\n", model.FirstFrame, model.LastFrame, model.FrameRate)
+		if len(model.MaterialsFile) != 0 {
+			fmt.Fprintf(w, "materials file:\t%s\n", model.MaterialsFile)
+		}
+		fmt.Fprintf(w, "materials:\t%d\n", info.materials)
+		fmt.Fprintf(w, "geometries:\t%d\n", len(model.Geometries))
+		fmt.Fprintf(w, "cameras:\t%d\n", info.cameras)
+		fmt.Fprintf(w, "nodes:\t%d\n", len(model.Nodes))
+		if model.RawNodes != nil {
+			fmt.Fprintf(w, "raw nodes:\t%d (from the first light instance on)\n", model.RawNodes.Count)
+		}
+		if len(info.unknown) != 0 {
+			fmt.Fprintf(w, "unknown chunks:\t%s\n", strings.Join(info.unknown, " "))
+		}
+		if len(info.chunkOrder) != 0 {
+			fmt.Fprintf(w, "chunk order:\t%s\n", strings.Join(info.chunkOrder, " "))
+		}
+		w.Flush()
+
+		if len(model.Geometries) == 0 {
+			return nil
+		}
+
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "GEOMETRY\tSOURCES\tVERTICES\tTRIANGLES\tJOINTS")
+		for _, geom := range model.Geometries {
+			var sources []string
+			vertices := 0
+			for _, src := range geom.Vertices {
+				sources = append(sources, src.Name)
+				if src.Stride != 0 && src.IsPosition() {
+					vertices = len(src.Data) / int(src.Stride)
+				}
+			}
+
+			triangles := 0
+			for _, indices := range geom.Materials {
+				triangles += int(indices.TrianglesCount)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n", geom.Name, strings.Join(sources, " "), vertices, triangles, len(geom.Skins.Joints))
+		}
+		return w.Flush()
+	}
+}
+
+func validateCommand(fs *flag.FlagSet) func(args []string) error {
+	var load loadFlags
+	load.register(fs)
+	// checksums are always verified
+	fs.Lookup("strict").DefValue = "true"
+	load.strict = true
+
+	return func(args []string) error {
+		if err := exactArgs(args, 1); err != nil {
+			return err
+		}
+
+		model, _, err := load.load(args[0])
+		if err != nil {
+			return err
+		}
+
+		problems := model.Validate()
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+
+		if len(problems) != 0 {
+			return fmt.Errorf("%s: %d problem(s) found", args[0], len(problems))
+		}
+
+		fmt.Printf("%s: ok\n", args[0])
+		return nil
+	}
+}
+
+func diffCommand(fs *flag.FlagSet) func(args []string) error {
+	var load loadFlags
+	load.register(fs)
+	limit := fs.Int("limit", 50, "maximum number of differences printed, 0 prints all of them")
+
+	return func(args []string) error {
+		if err := exactArgs(args, 2); err != nil {
+			return err
+		}
+
+		var trees [2]any
+		for i, filename := range args {
+			model, _, err := load.load(filename)
+			if err != nil {
+				return err
+			}
+
+			// both files are compared as their JSON so the paths are the ones of decode outputs
+			data, err := json.Marshal(model)
+			if err != nil {
+				return err
+			}
+			if err = json.Unmarshal(data, &trees[i]); err != nil {
+				return err
+			}
+		}
+
+		var differences []string
+		diffValues("", trees[0], trees[1], &differences)
+
+		for i, difference := range differences {
+			if *limit > 0 && i == *limit {
+				fmt.Printf("... and %d more\n", len(differences)-i)
+				break
+			}
+			fmt.Println(difference)
+		}
+
+		if len(differences) != 0 {
+			return fmt.Errorf("%s and %s differ in %d field(s)", args[0], args[1], len(differences))
+		}
+		return nil
+	}
+}
+
+// diffValues appends the paths of the values that differ between two decoded JSON trees
+func diffValues(path string, a, b any, differences *[]string) {
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			keys := make([]string, 0, len(a))
+			for key := range a {
+				keys = append(keys, key)
+			}
+			for key := range b {
+				if _, ok := a[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				field := key
+				if len(path) != 0 {
+					field = path + "." + key
+				}
+				diffValues(field, a[key], b[key], differences)
+			}
+			return
+		}
+	case []any:
+		if b, ok := b.([]any); ok {
+			for i := 0; i < len(a) && i < len(b); i++ {
+				diffValues(fmt.Sprintf("%s[%d]", path, i), a[i], b[i], differences)
+			}
+			if len(a) != len(b) {
+				*differences = append(*differences, fmt.Sprintf("%s: %d values != %d values", path, len(a), len(b)))
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*differences = append(*differences, fmt.Sprintf("%s: %s != %s", path, jsonValue(a), jsonValue(b)))
+	}
+}
+
+// jsonValue formats a decoded JSON value, objects and arrays are summarized
+func jsonValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "(none)"
+	case map[string]any:
+		return "{...}"
+	case []any:
+		return fmt.Sprintf("[%d values]", len(value))
+	}
+
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func migrateCommand(fs *flag.FlagSet) func(args []string) error {
+	var load loadFlags
+	load.register(fs)
+	output := fs.String("o", "", "output file (default: the input file)")
+	version := fs.Int("version", -1, fmt.Sprintf("scw version to migrate to (0 to %d)", scw.LatestVersion))
+	minor := fs.Int("minor", scw.MinorVersionAuto, "scw minor version of v0 outputs, the input one is kept if not set")
+
+	return func(args []string) error {
+		if err := exactArgs(args, 1); err != nil {
+			return err
+		}
+
+		if *version == -1 {
+			return usagef("expected a version, set it with --version")
+		}
+
+		if *version < 0 || *version > scw.LatestVersion {
+			return fmt.Errorf("%w: %d", scw.ErrUnsupportedVersion, *version)
+		}
+
+		model, format, err := load.load(args[0])
+		if err != nil {
+			return err
+		}
+
+		// the output is written in the format of the input, scw or scw JSON
+		if format.Name != "scw" && format.Name != "scw.json" {
+			return fmt.Errorf("%s is not an scw file, it is %s", args[0], format.Name)
+		}
+
+		if len(*output) == 0 {
+			*output = args[0]
+		}
+
+		report, err := scw.Migrate(model, uint16(*version), *minor)
+		if err != nil {
+			return err
+		}
+
+		if report.FromVersion != report.ToVersion {
+			fmt.Printf("migrated scw v%d to v%d\n", report.FromVersion, report.ToVersion)
+		}
+		for _, change := range report.Changes {
+			fmt.Println(" ", change)
+		}
+
+		return models.WriteFile(*output, format.Name, model)
+	}
+}
+
+func repairCommand(fs *flag.FlagSet) func(args []string) error {
+	output := fs.String("o", "", "output file (default: the input file)")
+
+	return func(args []string) error {
+		if err := exactArgs(args, 1); err != nil {
+			return err
+		}
+
+		if len(*output) == 0 {
+			*output = args[0]
+		}
+
+		return repair(args[0], *output)
+	}
+}
+
+func formatsCommand(fs *flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		if err := exactArgs(args, 0); err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FORMAT\tEXTENSIONS\tREAD\tWRITE")
+
+		yesNo := map[bool]string{true: "yes", false: "no"}
+		for _, format := range models.Formats() {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", format.Name, strings.Join(format.Extensions, " "), yesNo[format.CanRead()], yesNo[format.CanWrite()])
+		}
+
+		return w.Flush()
+	}
+}
+
+func quantizationReport(m *scw.File) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GEOMETRY\tSOURCE\tSCALE\tMAX ERROR")
+
+	for _, stats := range m.QuantizationReport() {
+		if stats.Err != nil {
+			fmt.Fprintf(w, "%s\t%s\t%g\t%v\n", stats.Geometry, stats.Source, stats.Scale, stats.Err)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%g\t%g\n", stats.Geometry, stats.Source, stats.Scale, stats.MaxError)
+		}
+	}
+
+	w.Flush()
+}
+
+// repair rewrites the checksums of an scw file, it is read at once so it can be repaired in place
+func repair(inputFile, outputFile string) error {
+	data, err := os.ReadFile(inputFile)
+	if err != nil {
+		return err
+	}
+
+	var repaired []scw.CRCError
+	err = models.CreateFile(outputFile, func(w io.Writer) (err error) {
+		repaired, err = scw.RepairCRC(bytes.NewReader(data), w)
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range repaired {
+		fmt.Printf("repaired %s chunk at offset %d (crc32 %08x -> %08x)\n", chunk.Tag, chunk.Offset, chunk.Stored, chunk.Computed)
+	}
+	fmt.Printf("%d chunk(s) repaired\n", len(repaired))
+	return nil
+}
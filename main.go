@@ -1,129 +1,234 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/PeterHackz/conv3d/models"
+	"github.com/PeterHackz/conv3d/models/scene"
 	"github.com/PeterHackz/conv3d/models/scw"
 )
 
-func main() {
+// usageError is a wrong use of a command, its usage is printed and the exit code is 2
+type usageError struct {
+	msg string
+}
 
-	inputFile := flag.String("in-file", "", "the input file path")
-	outputFile := flag.String("out-file", "", "the output file")
-	scwSubVersion := flag.Int("minor-version", 5, "scw minor version (5 for v8+, not set for others)")
-	scwOutVersion := flag.Int("out-version", 2, "scw output version")
+func (e *usageError) Error() string {
+	return e.msg
+}
 
-	var scw2scw bool
-	flag.BoolVar(&scw2scw, "scw2scw", false, "converts an scw model to another scw version")
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
 
-	flag.Parse()
+type command struct {
+	name    string
+	args    string // positional arguments shown in the usage
+	summary string
+	// flags registers the flags of the command, the returned function runs it with its positional arguments
+	flags func(fs *flag.FlagSet) func(args []string) error
+}
 
-	if len(*inputFile) == 0 {
-		panic("expected an input file")
+var commands []command
+
+func init() {
+	commands = []command{
+		{"decode", "<input>", "decode a model to scw JSON", decodeCommand},
+		{"encode", "<input.scw.json>", "encode scw JSON to a binary scw file", encodeCommand},
+		{"convert", "--to <format> <input>", "convert a model to another format", convertCommand},
+		{"info", "<input>", "print the version, chunks and contents of a model", infoCommand},
+		{"validate", "<input>", "check the checksums, references, indices and skins of a model", validateCommand},
+		{"diff", "<a> <b>", "print the fields that differ between two models", diffCommand},
+		{"migrate", "--version <n> <input>", "convert an scw model to another scw version", migrateCommand},
+		{"repair", "<input.scw>", "rewrite the crc32 of every chunk of an scw file", repairCommand},
+		{"formats", "", "list the supported formats", formatsCommand},
 	}
+}
 
-	outputJson := false
-
-	if !scw2scw {
-		if strings.HasSuffix(*inputFile, "scw") {
-			outputJson = true
-			if len(*outputFile) == 0 {
-				*outputFile = "output.scw.json"
-			}
-		} else if strings.HasSuffix(*inputFile, "json") {
-			if len(*outputFile) == 0 {
-				*outputFile = "output.scw"
-			}
-		} else {
-			if len(*outputFile) == 0 {
-				*outputFile = "output.scw"
-			}
-		}
-	} else {
-		if len(*outputFile) == 0 {
-			*outputFile = *inputFile
+func lookupCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
 		}
 	}
+	return nil
+}
 
-	model, err := models.LoadFromFile(*inputFile)
-	if err != nil {
-		panic(err)
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: conv3d <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s%s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `run "conv3d <command> --help" for the flags of a command`)
+}
 
-	if scw2scw || outputJson {
+// parseArgs parses args with fs, flags can be placed before, between or after the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
 
-		if *scwSubVersion != 0 {
-			switch m := model.(type) {
-			case *scw.File:
-				m.MinorVersion = *scwSubVersion
-			}
+		if fs.NArg() == 0 {
+			return positional, nil
 		}
 
-		if err := model.Load(); err != nil {
-			panic(err)
+		// everything after "--" is positional
+		if len(args) > fs.NArg() && args[len(args)-fs.NArg()-1] == "--" {
+			return append(positional, fs.Args()...), nil
 		}
 
-	} else {
-		if err := model.LoadJSON(); err != nil {
-			panic(err)
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// exactArgs returns a usage error unless there are count positional arguments
+func exactArgs(args []string, count int) error {
+	switch {
+	case len(args) < count:
+		return usagef("expected %d argument(s), got %d", count, len(args))
+	case len(args) > count:
+		return usagef("unexpected argument: %s", args[count])
+	}
+	return nil
+}
+
+// runCommand runs cmd with args and returns the exit code of conv3d
+func runCommand(cmd *command, args []string) int {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: conv3d %s [flags] %s\n\n%s\n", cmd.name, cmd.args, cmd.summary)
+
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(fs.Output(), "\nflags:")
+			fs.PrintDefaults()
 		}
 	}
 
-	file, err := os.Create(*outputFile)
-	if err != nil {
-		panic(err)
+	run := cmd.flags(fs)
+
+	positional, err := parseArgs(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		// the flag package already printed the error and the usage
+		return 2
 	}
 
-	defer func() {
-		if err := file.Close(); err != nil {
-			panic(err)
+	if err = run(positional); err != nil {
+		fmt.Fprintf(os.Stderr, "conv3d %s: %v\n", cmd.name, err)
+
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, `run "conv3d %s --help" for usage`+"\n", cmd.name)
+			return 2
 		}
-	}()
-
-	switch m := model.(type) {
-	case *scw.File:
-		if *scwOutVersion == 2 {
-			m.Version = 2
-			m.MinorVersion = 0
-			if m.Unknown == -1 {
-				m.Unknown = 0
-			}
-			for _, geom := range m.Geometries {
-				for i := range geom.Vertices {
-					if geom.Vertices[i].Name == "VERTEX" {
-						geom.Vertices[i].Name = "POSITION"
-					}
-				}
-			}
-		} else {
-			m.Unknown = -1
-			m.MinorVersion = 5
-			for _, geom := range m.Geometries {
-				for i := range geom.Vertices {
-					if geom.Vertices[i].Name == "POSITION" {
-						geom.Vertices[i].Name = "VERTEX"
-					}
-				}
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	// warnings of the commands and format packages
+	log.SetFlags(0)
+	log.SetPrefix("conv3d: ")
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) != 0 {
+			if cmd := lookupCommand(args[0]); cmd != nil {
+				os.Exit(runCommand(cmd, []string{"--help"}))
 			}
 		}
+		usage()
+		return
 	}
 
-	if !scw2scw && outputJson {
-		r, err := json.MarshalIndent(model, "", "  ")
-		if err != nil {
-			panic(err)
-		}
+	cmd := lookupCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "conv3d: unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
 
-		if _, err = file.WriteString(string(r)); err != nil {
-			panic(err)
-		}
-	} else {
-		if _, err = file.Write(model.Encode()); err != nil {
-			panic(err)
+	os.Exit(runCommand(cmd, args))
+}
+
+// warn prints the warnings of the format packages
+func warn(msg string) {
+	log.Print(msg)
+}
+
+// loadFlags are the flags of the commands loading models
+type loadFlags struct {
+	strict, rawSamples bool
+	minorVersion       int
+}
+
+func (l *loadFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&l.strict, "strict", false, "fail on scw chunks with a wrong crc32")
+	fs.BoolVar(&l.rawSamples, "raw-samples", false, "keep the raw int16 vertex samples of scw files to encode them back bit for bit")
+	fs.IntVar(&l.minorVersion, "minor-version", scw.MinorVersionAuto, "scw minor version of v0 files (5 for v8+), detected from the skin weights if not set")
+}
+
+func (l *loadFlags) options() models.Options {
+	opts := models.Options{Strict: l.strict, RawSamples: l.rawSamples, Warn: warn}
+	if l.minorVersion != scw.MinorVersionAuto {
+		opts.MinorVersion = &l.minorVersion
+	}
+	return opts
+}
+
+func (l *loadFlags) load(filename string) (*scw.File, *models.Format, error) {
+	return models.LoadFromFile(filename, l.options())
+}
+
+func (l *loadFlags) loadScene(filename string) (*scene.Scene, *models.Format, error) {
+	return models.LoadScene(filename, l.options())
+}
+
+// trimExtension removes the extension of filename, the registered ones like .scw.json are removed whole
+func trimExtension(filename string) string {
+	if format, err := models.ByExtension(filename); err == nil {
+		lower := strings.ToLower(filename)
+		for _, ext := range format.Extensions {
+			if strings.HasSuffix(lower, ext) {
+				return filename[:len(filename)-len(ext)]
+			}
 		}
 	}
+	return strings.TrimSuffix(filename, filepath.Ext(filename))
+}
+
+// outputFile returns output, or the input file with the extension of format when it is not set
+func outputFile(output, input string, format *models.Format) (string, error) {
+	if len(output) != 0 {
+		return output, nil
+	}
+
+	output = trimExtension(input) + format.Extensions[0]
+	if output == input {
+		return "", usagef("the output file would overwrite %s, set it with -o", input)
+	}
+	return output, nil
 }
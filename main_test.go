@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models"
+)
+
+func TestParseArgs(t *testing.T) {
+	for _, test := range []struct {
+		args       []string
+		positional []string
+		output     string
+		strict     bool
+	}{
+		{nil, nil, "", false},
+		{[]string{"in.scw"}, []string{"in.scw"}, "", false},
+		{[]string{"-o", "out.scw", "in.scw"}, []string{"in.scw"}, "out.scw", false},
+		{[]string{"in.scw", "-o", "out.scw"}, []string{"in.scw"}, "out.scw", false},
+		{[]string{"a.scw", "--strict", "b.scw", "-o=out.scw"}, []string{"a.scw", "b.scw"}, "out.scw", true},
+		{[]string{"--", "-o", "in.scw"}, []string{"-o", "in.scw"}, "", false},
+		{[]string{"--strict", "in.scw", "--", "--strict"}, []string{"in.scw", "--strict"}, "", true},
+		{[]string{"in.scw", "--", "--"}, []string{"in.scw", "--"}, "", false},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		output := fs.String("o", "", "")
+		strict := fs.Bool("strict", false, "")
+
+		positional, err := parseArgs(fs, test.args)
+		if err != nil {
+			t.Errorf("%q: %v", test.args, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(positional, test.positional) || *output != test.output || *strict != test.strict {
+			t.Errorf("%q: got %q, -o %q, --strict %v, expected %q, -o %q, --strict %v",
+				test.args, positional, *output, *strict, test.positional, test.output, test.strict)
+		}
+	}
+}
+
+func TestParseArgsUnknownFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	if _, err := parseArgs(fs, []string{"in.scw", "--unknown"}); err == nil {
+		t.Fatal("an unknown flag after the positional arguments was accepted")
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	for _, test := range []struct {
+		output, input, format string
+		expected              string // empty for a usage error
+	}{
+		{"", "model.scw", "scw.json", "model.scw.json"},
+		{"", "model.scw.json", "scw", "model.scw"},
+		{"", "dir/Model.SCW.JSON", "glb", "dir/Model.glb"},
+		{"", "model.glb", "gltf", "model.gltf"},
+		{"", "model.tar.gz", "obj", "model.tar.obj"},
+		{"", "model.json", "scw.json", "model.scw.json"},
+		{"", "model", "dae", "model.dae"},
+		{"out.obj", "model.scw", "dae", "out.obj"},
+		{"model.scw", "model.scw", "scw", "model.scw"},
+		// the input would be overwritten
+		{"", "model.scw", "scw", ""},
+		{"", "model.scw.json", "scw.json", ""},
+	} {
+		output, err := outputFile(test.output, test.input, models.Lookup(test.format))
+
+		if len(test.expected) == 0 {
+			if _, ok := err.(*usageError); !ok {
+				t.Errorf("%s to %s: got %q %v, expected a usage error", test.input, test.format, output, err)
+			}
+			continue
+		}
+
+		if err != nil || output != test.expected {
+			t.Errorf("%s to %s: got %q %v, expected %q", test.input, test.format, output, err, test.expected)
+		}
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		a, b        any
+		differences []string
+	}{
+		{"equal", map[string]any{"a": 1.0, "b": []any{"x"}}, map[string]any{"a": 1.0, "b": []any{"x"}}, nil},
+		{"value", map[string]any{"a": 1.0}, map[string]any{"a": 2.0}, []string{"a: 1 != 2"}},
+		{"missing key", map[string]any{"a": 1.0}, map[string]any{"b": "x"}, []string{`a: 1 != (none)`, `b: (none) != "x"`}},
+		{"nested", map[string]any{"a": map[string]any{"b": []any{1.0, 2.0}}}, map[string]any{"a": map[string]any{"b": []any{1.0, 3.0}}}, []string{"a.b[1]: 2 != 3"}},
+		{"length", []any{1.0, 2.0}, []any{1.0}, []string{": 2 values != 1 values"}},
+		{"type", map[string]any{"a": []any{1.0}}, map[string]any{"a": map[string]any{}}, []string{"a: [1 values] != {...}"}},
+		{"null", map[string]any{"a": nil}, map[string]any{"a": false}, []string{"a: (none) != false"}},
+	} {
+		var differences []string
+		diffValues("", test.a, test.b, &differences)
+
+		if !reflect.DeepEqual(differences, test.differences) {
+			t.Errorf("%s: got %q, expected %q", test.name, differences, test.differences)
+		}
+	}
+}
+
+func TestRunCommandExitCodes(t *testing.T) {
+	// the commands print to stdout and stderr
+	for _, file := range []**os.File{&os.Stdout, &os.Stderr} {
+		null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := *file
+		*file = null
+		t.Cleanup(func() {
+			*file = saved
+			null.Close()
+		})
+	}
+
+	dir := t.TempDir()
+	input := filepath.Join("models", "scw", "testdata", "static.scw")
+
+	for _, test := range []struct {
+		command string
+		args    []string
+		code    int
+	}{
+		{"formats", nil, 0},
+		{"formats", []string{"--help"}, 0},
+		{"info", []string{input}, 0},
+		{"decode", []string{input, "-o", filepath.Join(dir, "static.scw.json")}, 0},
+		{"validate", []string{input}, 0},
+		{"diff", []string{input, input}, 0},
+		// failures
+		{"info", []string{filepath.Join(dir, "missing.scw")}, 1},
+		{"diff", []string{input, filepath.Join("models", "scw", "testdata", "animated.scw")}, 1},
+		{"migrate", []string{"--version", "9", input}, 1},
+		// wrong usage
+		{"formats", []string{"extra"}, 2},
+		{"info", nil, 2},
+		{"info", []string{input, "--unknown"}, 2},
+		{"convert", []string{input}, 2},
+		{"encode", []string{input}, 2},
+	} {
+		if code := runCommand(lookupCommand(test.command), test.args); code != test.code {
+			t.Errorf("%s %q: exit code %d, expected %d", test.command, test.args, code, test.code)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package dae
+
+import "encoding/xml"
+
+// COLLADA 1.4.1 document, only the parts used by scw are mapped
+//
+// reference: https://www.khronos.org/files/collada_spec_1_4.pdf
+
+const (
+	Namespace = "http://www.collada.org/2005/11/COLLADASchema"
+	Version   = "1.4.1"
+)
+
+type Collada struct {
+	XMLName xml.Name `xml:"COLLADA"`
+	XMLNS   string   `xml:"xmlns,attr,omitempty"`
+	Version string   `xml:"version,attr"`
+	Asset   Asset    `xml:"asset"`
+
+	LibraryImages       *LibraryImages       `xml:"library_images"`
+	LibraryEffects      *LibraryEffects      `xml:"library_effects"`
+	LibraryMaterials    *LibraryMaterials    `xml:"library_materials"`
+	LibraryGeometries   *LibraryGeometries   `xml:"library_geometries"`
+	LibraryControllers  *LibraryControllers  `xml:"library_controllers"`
+	LibraryCameras      *LibraryCameras      `xml:"library_cameras"`
+	LibraryAnimations   *LibraryAnimations   `xml:"library_animations"`
+	LibraryVisualScenes *LibraryVisualScenes `xml:"library_visual_scenes"`
+
+	Scene *Scene `xml:"scene"`
+}
+
+type Asset struct {
+	Contributor *Contributor `xml:"contributor"`
+	Created     string       `xml:"created"`
+	Modified    string       `xml:"modified"`
+	Unit        *Unit        `xml:"unit"`
+	UpAxis      string       `xml:"up_axis,omitempty"`
+}
+
+type Contributor struct {
+	AuthoringTool string `xml:"authoring_tool,omitempty"`
+}
+
+type Unit struct {
+	Name  string  `xml:"name,attr,omitempty"`
+	Meter float64 `xml:"meter,attr"`
+}
+
+type LibraryImages struct {
+	Images []Image `xml:"image"`
+}
+
+type Image struct {
+	ID       string `xml:"id,attr,omitempty"`
+	Name     string `xml:"name,attr,omitempty"`
+	InitFrom string `xml:"init_from"`
+}
+
+type LibraryEffects struct {
+	Effects []Effect `xml:"effect"`
+}
+
+type Effect struct {
+	ID      string        `xml:"id,attr,omitempty"`
+	Name    string        `xml:"name,attr,omitempty"`
+	Profile ProfileCommon `xml:"profile_COMMON"`
+}
+
+type ProfileCommon struct {
+	NewParams []NewParam      `xml:"newparam"`
+	Technique CommonTechnique `xml:"technique"`
+}
+
+type NewParam struct {
+	SID       string     `xml:"sid,attr"`
+	Surface   *Surface   `xml:"surface"`
+	Sampler2D *Sampler2D `xml:"sampler2D"`
+}
+
+type Surface struct {
+	Type     string `xml:"type,attr"`
+	InitFrom string `xml:"init_from"`
+}
+
+type Sampler2D struct {
+	Source string `xml:"source"`
+}
+
+type CommonTechnique struct {
+	SID     string   `xml:"sid,attr,omitempty"`
+	Phong   *Shading `xml:"phong"`
+	Blinn   *Shading `xml:"blinn"`
+	Lambert *Shading `xml:"lambert"`
+}
+
+// Shading is shared by phong, blinn and lambert (which just has no specular)
+type Shading struct {
+	Emission     *ColorOrTexture `xml:"emission"`
+	Ambient      *ColorOrTexture `xml:"ambient"`
+	Diffuse      *ColorOrTexture `xml:"diffuse"`
+	Specular     *ColorOrTexture `xml:"specular"`
+	Transparent  *ColorOrTexture `xml:"transparent"`
+	Transparency *FloatParam     `xml:"transparency"`
+}
+
+type ColorOrTexture struct {
+	Color   *Values     `xml:"color"`
+	Texture *TextureRef `xml:"texture"`
+}
+
+type TextureRef struct {
+	Texture  string `xml:"texture,attr"`
+	TexCoord string `xml:"texcoord,attr"`
+}
+
+type FloatParam struct {
+	Float float64 `xml:"float"`
+}
+
+// Values is any element holding a whitespace separated list (matrices, colors, arrays...)
+type Values struct {
+	ID    string `xml:"id,attr,omitempty"`
+	SID   string `xml:"sid,attr,omitempty"`
+	Count *int   `xml:"count,attr"`
+	Data  string `xml:",chardata"`
+}
+
+type LibraryMaterials struct {
+	Materials []Material `xml:"material"`
+}
+
+type Material struct {
+	ID             string      `xml:"id,attr,omitempty"`
+	Name           string      `xml:"name,attr,omitempty"`
+	InstanceEffect InstanceURL `xml:"instance_effect"`
+}
+
+type InstanceURL struct {
+	URL string `xml:"url,attr"`
+}
+
+type LibraryGeometries struct {
+	Geometries []Geometry `xml:"geometry"`
+}
+
+type Geometry struct {
+	ID   string `xml:"id,attr,omitempty"`
+	Name string `xml:"name,attr,omitempty"`
+	Mesh *Mesh  `xml:"mesh"`
+}
+
+type Mesh struct {
+	Sources   []Source    `xml:"source"`
+	Vertices  Vertices    `xml:"vertices"`
+	Triangles []Primitive `xml:"triangles"`
+	Polylist  []Primitive `xml:"polylist"`
+}
+
+type Source struct {
+	ID         string           `xml:"id,attr,omitempty"`
+	Name       string           `xml:"name,attr,omitempty"`
+	FloatArray *Values          `xml:"float_array"`
+	NameArray  *Values          `xml:"Name_array"`
+	IDREFArray *Values          `xml:"IDREF_array"`
+	Technique  *SourceTechnique `xml:"technique_common"`
+}
+
+type SourceTechnique struct {
+	Accessor Accessor `xml:"accessor"`
+}
+
+type Accessor struct {
+	Source string  `xml:"source,attr"`
+	Count  int     `xml:"count,attr"`
+	Stride int     `xml:"stride,attr,omitempty"`
+	Params []Param `xml:"param"`
+}
+
+type Param struct {
+	Name string `xml:"name,attr,omitempty"`
+	Type string `xml:"type,attr"`
+}
+
+type Vertices struct {
+	ID     string  `xml:"id,attr,omitempty"`
+	Inputs []Input `xml:"input"`
+}
+
+// Input offset and set are only written for shared inputs (primitives, vertex weights)
+type Input struct {
+	Semantic string `xml:"semantic,attr"`
+	Source   string `xml:"source,attr"`
+	Offset   *int   `xml:"offset,attr"`
+	Set      *int   `xml:"set,attr"`
+}
+
+// Primitive is a triangles or polylist element, polylists also have a vcount
+type Primitive struct {
+	Material string  `xml:"material,attr,omitempty"`
+	Count    int     `xml:"count,attr"`
+	Inputs   []Input `xml:"input"`
+	VCount   string  `xml:"vcount,omitempty"`
+	P        string  `xml:"p"`
+}
+
+type LibraryControllers struct {
+	Controllers []Controller `xml:"controller"`
+}
+
+type Controller struct {
+	ID   string `xml:"id,attr,omitempty"`
+	Name string `xml:"name,attr,omitempty"`
+	Skin *Skin  `xml:"skin"`
+}
+
+type Skin struct {
+	Source          string        `xml:"source,attr"`
+	BindShapeMatrix string        `xml:"bind_shape_matrix,omitempty"`
+	Sources         []Source      `xml:"source"`
+	Joints          Inputs        `xml:"joints"`
+	VertexWeights   VertexWeights `xml:"vertex_weights"`
+}
+
+type Inputs struct {
+	Inputs []Input `xml:"input"`
+}
+
+type VertexWeights struct {
+	Count  int     `xml:"count,attr"`
+	Inputs []Input `xml:"input"`
+	VCount string  `xml:"vcount"`
+	V      string  `xml:"v"`
+}
+
+type LibraryCameras struct {
+	Cameras []Camera `xml:"camera"`
+}
+
+type Camera struct {
+	ID     string `xml:"id,attr,omitempty"`
+	Name   string `xml:"name,attr,omitempty"`
+	Optics Optics `xml:"optics"`
+}
+
+type Optics struct {
+	Technique OpticsTechnique `xml:"technique_common"`
+}
+
+type OpticsTechnique struct {
+	Perspective *Perspective `xml:"perspective"`
+}
+
+type Perspective struct {
+	XFov        *float32 `xml:"xfov"`
+	YFov        *float32 `xml:"yfov"`
+	AspectRatio *float32 `xml:"aspect_ratio"`
+	ZNear       float32  `xml:"znear"`
+	ZFar        float32  `xml:"zfar"`
+}
+
+type LibraryAnimations struct {
+	Animations []Animation `xml:"animation"`
+}
+
+type Animation struct {
+	ID         string      `xml:"id,attr,omitempty"`
+	Name       string      `xml:"name,attr,omitempty"`
+	Animations []Animation `xml:"animation"`
+	Sources    []Source    `xml:"source"`
+	Samplers   []Sampler   `xml:"sampler"`
+	Channels   []Channel   `xml:"channel"`
+}
+
+type Sampler struct {
+	ID     string  `xml:"id,attr,omitempty"`
+	Inputs []Input `xml:"input"`
+}
+
+type Channel struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type LibraryVisualScenes struct {
+	VisualScenes []VisualScene `xml:"visual_scene"`
+}
+
+type VisualScene struct {
+	ID    string `xml:"id,attr,omitempty"`
+	Name  string `xml:"name,attr,omitempty"`
+	Nodes []Node `xml:"node"`
+}
+
+type Node struct {
+	ID   string `xml:"id,attr,omitempty"`
+	SID  string `xml:"sid,attr,omitempty"`
+	Name string `xml:"name,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+
+	// transformations are applied in order, so they are kept in a single list (matrix, translate, rotate, scale...)
+	Transforms []Transform `xml:",any"`
+
+	InstanceCameras     []InstanceCamera     `xml:"instance_camera"`
+	InstanceControllers []InstanceController `xml:"instance_controller"`
+	InstanceGeometries  []InstanceGeometry   `xml:"instance_geometry"`
+	Nodes               []Node               `xml:"node"`
+}
+
+type Transform struct {
+	XMLName xml.Name
+	SID     string `xml:"sid,attr,omitempty"`
+	Data    string `xml:",chardata"`
+}
+
+type InstanceCamera struct {
+	URL   string `xml:"url,attr"`
+	Extra *Extra `xml:"extra"`
+}
+
+type InstanceController struct {
+	URL          string        `xml:"url,attr"`
+	Skeletons    []string      `xml:"skeleton"`
+	BindMaterial *BindMaterial `xml:"bind_material"`
+}
+
+type InstanceGeometry struct {
+	URL          string        `xml:"url,attr"`
+	BindMaterial *BindMaterial `xml:"bind_material"`
+}
+
+type BindMaterial struct {
+	Technique BindTechnique `xml:"technique_common"`
+}
+
+type BindTechnique struct {
+	InstanceMaterials []InstanceMaterial `xml:"instance_material"`
+}
+
+type InstanceMaterial struct {
+	Symbol           string            `xml:"symbol,attr"`
+	Target           string            `xml:"target,attr"`
+	BindVertexInputs []BindVertexInput `xml:"bind_vertex_input"`
+}
+
+type BindVertexInput struct {
+	Semantic      string `xml:"semantic,attr"`
+	InputSemantic string `xml:"input_semantic,attr"`
+	InputSet      int    `xml:"input_set,attr"`
+}
+
+// Extra keeps scw data with no COLLADA equivalent
+type Extra struct {
+	Technique ExtraTechnique `xml:"technique"`
+}
+
+type ExtraTechnique struct {
+	Profile string `xml:"profile,attr"`
+	Target  string `xml:"target,omitempty"`
+}
+
+type Scene struct {
+	InstanceVisualScene InstanceURL `xml:"instance_visual_scene"`
+}
@@ -0,0 +1,703 @@
+package dae
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PeterHackz/conv3d/models/internal/atomicfile"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+// extraProfile is the technique profile of scw specific <extra> data
+const extraProfile = "SCW"
+
+type exporter struct {
+	file *scw.File
+	doc  *Collada
+
+	images map[string]string // texture path -> image id
+	joints map[string]bool   // nodes used as skin joints
+}
+
+// Export converts a loaded scw File to a COLLADA document
+//
+// scw nodes, instances (GEOM, CONT, CAME) and material bindings map one-to-one to COLLADA ones,
+// skinned geometries become controllers and key frames are sampled as transform matrices
+func Export(file *scw.File) (*Collada, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	e := &exporter{
+		file: file,
+		doc: &Collada{
+			XMLNS:   Namespace,
+			Version: Version,
+			Asset: Asset{
+				Contributor: &Contributor{AuthoringTool: "conv3d"},
+				Created:     now,
+				Modified:    now,
+				Unit:        &Unit{Name: "meter", Meter: 1},
+				UpAxis:      "Y_UP",
+			},
+		},
+		images: make(map[string]string),
+		joints: make(map[string]bool),
+	}
+
+	for _, mat := range file.Materials {
+		e.exportMaterial(mat)
+	}
+
+	for _, geom := range file.Geometries {
+		if err := e.exportGeometry(geom); err != nil {
+			return nil, err
+		}
+
+		if len(geom.Skins.Joints) > 0 {
+			e.exportController(geom)
+		}
+	}
+
+	for _, cam := range file.Cameras {
+		e.exportCamera(cam)
+	}
+
+	if err := e.exportScene(); err != nil {
+		return nil, err
+	}
+
+	e.exportAnimations()
+
+	return e.doc, nil
+}
+
+// WriteFile exports file as a .dae document
+func WriteFile(filename string, file *scw.File) error {
+	doc, err := Export(file)
+	if err != nil {
+		return err
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(filename, append([]byte(xml.Header), data...))
+}
+
+// ID returns a valid xs:ID (the type of every COLLADA id and sid) built from an scw name
+func ID(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		valid := r == '_' || r == '-' || r == '.' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+		if !valid || i == 0 && ('0' <= r && r <= '9' || r == '-' || r == '.') {
+			b.WriteByte('_')
+			if valid {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+func formatFloats[T float32 | float64](values []T) string {
+	bitSize := 64
+	if _, ok := any(values).([]float32); ok {
+		bitSize = 32
+	}
+
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatFloat(float64(v), 'g', -1, bitSize)
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatInts[T uint32 | int](values []T) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatUint(uint64(v), 10)
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatMatrix writes a decoded scw matrix in the COLLADA row major order
+func formatMatrix(m *scw.Matrix4x4) string {
+	values := make([]float32, 0, 16)
+	for r := range 4 {
+		values = append(values, m[r][:]...)
+	}
+	return formatFloats(values)
+}
+
+func floatSource(id string, values []float64, params ...string) Source {
+	stride := len(params)
+	source := Source{
+		ID:         id,
+		FloatArray: &Values{ID: id + "-array", Count: ptr(len(values)), Data: formatFloats(values)},
+		Technique: &SourceTechnique{Accessor: Accessor{
+			Source: "#" + id + "-array",
+			Count:  len(values) / stride,
+			Stride: stride,
+		}},
+	}
+
+	for _, param := range params {
+		source.Technique.Accessor.Params = append(source.Technique.Accessor.Params, Param{Name: param, Type: "float"})
+	}
+
+	return source
+}
+
+func nameSource(id string, names []string, param string) Source {
+	return Source{
+		ID:        id,
+		NameArray: &Values{ID: id + "-array", Count: ptr(len(names)), Data: strings.Join(names, " ")},
+		Technique: &SourceTechnique{Accessor: Accessor{
+			Source: "#" + id + "-array",
+			Count:  len(names),
+			Stride: 1,
+			Params: []Param{{Name: param, Type: "name"}},
+		}},
+	}
+}
+
+func (e *exporter) image(path string) string {
+	if id, ok := e.images[path]; ok {
+		return id
+	}
+
+	if e.doc.LibraryImages == nil {
+		e.doc.LibraryImages = new(LibraryImages)
+	}
+
+	id := fmt.Sprintf("image-%d", len(e.images))
+	e.doc.LibraryImages.Images = append(e.doc.LibraryImages.Images, Image{ID: id, Name: path, InitFrom: path})
+	e.images[path] = id
+	return id
+}
+
+// colorOrTexture converts an scw Variable, textures go through the surface/sampler params of the effect
+func (e *exporter) colorOrTexture(effect *Effect, variable *scw.Variable) *ColorOrTexture {
+	if !variable.UseText2D {
+		c := variable.Color.Floats()
+		return &ColorOrTexture{Color: &Values{Data: formatFloats(c[:])}}
+	}
+
+	image := e.image(variable.Texture2D)
+	profile := &effect.Profile
+
+	sampler := image + "-sampler"
+	found := false
+	for _, param := range profile.NewParams {
+		found = found || param.SID == sampler
+	}
+
+	if !found {
+		profile.NewParams = append(profile.NewParams,
+			NewParam{SID: image + "-surface", Surface: &Surface{Type: "2D", InitFrom: image}},
+			NewParam{SID: sampler, Sampler2D: &Sampler2D{Source: image + "-surface"}},
+		)
+	}
+
+	return &ColorOrTexture{Texture: &TextureRef{Texture: sampler, TexCoord: "TEXCOORD0"}}
+}
+
+func (e *exporter) exportMaterial(mat *scw.Material) {
+	if e.doc.LibraryMaterials == nil {
+		e.doc.LibraryMaterials = new(LibraryMaterials)
+		e.doc.LibraryEffects = new(LibraryEffects)
+	}
+
+	vars := &mat.Variables
+	id := ID(mat.Name)
+
+	effect := Effect{ID: id + "-effect", Name: mat.Name}
+
+	phong := &Shading{
+		Emission: e.colorOrTexture(&effect, &vars.Emission),
+		Ambient:  e.colorOrTexture(&effect, &vars.Ambient),
+		Diffuse:  e.colorOrTexture(&effect, &vars.Diffuse),
+		Specular: e.colorOrTexture(&effect, &vars.Specular),
+	}
+
+	if len(vars.OpacityTex2D) != 0 {
+		phong.Transparent = e.colorOrTexture(&effect, &scw.Variable{UseText2D: true, Texture2D: vars.OpacityTex2D})
+	}
+
+	if vars.Opacity > 0 {
+		phong.Transparency = &FloatParam{Float: float64(vars.Opacity)}
+	}
+
+	effect.Profile.Technique = CommonTechnique{SID: "common", Phong: phong}
+
+	e.doc.LibraryEffects.Effects = append(e.doc.LibraryEffects.Effects, effect)
+	e.doc.LibraryMaterials.Materials = append(e.doc.LibraryMaterials.Materials, Material{
+		ID:             id + "-material",
+		Name:           mat.Name,
+		InstanceEffect: InstanceURL{URL: "#" + effect.ID},
+	})
+}
+
+func sourceParams(src *scw.SourceArray) []string {
+	var params []string
+
+	switch src.Name {
+	case "POSITION", "VERTEX", "NORMAL":
+		params = []string{"X", "Y", "Z"}
+	case "TEXCOORD":
+		params = []string{"S", "T", "P"}
+	case "COLOR":
+		params = []string{"R", "G", "B", "A"}
+	}
+
+	out := make([]string, src.Stride)
+	for i := range out {
+		if i < len(params) {
+			out[i] = params[i]
+		}
+	}
+	return out
+}
+
+func (e *exporter) exportGeometry(geom *scw.Geometry) error {
+	if e.doc.LibraryGeometries == nil {
+		e.doc.LibraryGeometries = new(LibraryGeometries)
+	}
+
+	id := ID(geom.Name) + "-mesh"
+	mesh := &Mesh{Vertices: Vertices{ID: id + "-vertices"}}
+
+	// every source is a shared input at its own offset, the position one goes through <vertices>
+	var inputs []Input
+
+	for i := range geom.Vertices {
+		src := &geom.Vertices[i]
+		if src.Stride == 0 {
+			return fmt.Errorf("geometry %s: source %s has a stride of 0", geom.Name, src.Name)
+		}
+
+		sourceID := fmt.Sprintf("%s-%s-%d", id, strings.ToLower(src.Name), i)
+		mesh.Sources = append(mesh.Sources, floatSource(sourceID, src.Data, sourceParams(src)...))
+
+		input := Input{
+			Semantic: src.Name,
+			Source:   "#" + sourceID,
+			Offset:   ptr(int(src.Index)),
+		}
+
+		switch src.Name {
+		case "POSITION", "VERTEX":
+			mesh.Vertices.Inputs = append(mesh.Vertices.Inputs, Input{Semantic: "POSITION", Source: "#" + sourceID})
+			input.Semantic = "VERTEX"
+			input.Source = "#" + mesh.Vertices.ID
+		case "TEXCOORD", "COLOR":
+			input.Set = ptr(int(src.SourceIndex))
+		}
+
+		inputs = append(inputs, input)
+	}
+
+	if len(mesh.Vertices.Inputs) == 0 {
+		return fmt.Errorf("geometry %s has no POSITION source", geom.Name)
+	}
+
+	for _, prim := range geom.Materials {
+		if len(prim.IndexBuffer) != 3*int(prim.TrianglesCount)*int(prim.InputsCount) {
+			return fmt.Errorf("geometry %s: index array %s does not match its triangles count", geom.Name, prim.Name)
+		}
+
+		mesh.Triangles = append(mesh.Triangles, Primitive{
+			Material: prim.Name,
+			Count:    int(prim.TrianglesCount),
+			Inputs:   inputs,
+			P:        formatInts(prim.IndexBuffer),
+		})
+	}
+
+	e.doc.LibraryGeometries.Geometries = append(e.doc.LibraryGeometries.Geometries, Geometry{
+		ID:   id,
+		Name: geom.Name,
+		Mesh: mesh,
+	})
+
+	return nil
+}
+
+func (e *exporter) exportController(geom *scw.Geometry) {
+	if e.doc.LibraryControllers == nil {
+		e.doc.LibraryControllers = new(LibraryControllers)
+	}
+
+	id := ID(geom.Name) + "-skin"
+
+	skin := &Skin{Source: "#" + ID(geom.Name) + "-mesh"}
+
+	if geom.HasBindMatrix {
+		skin.BindShapeMatrix = formatMatrix(&geom.BindMatrix)
+	}
+
+	joints := make([]string, len(geom.Skins.Joints))
+	var matrices []float64
+	for i, joint := range geom.Skins.Joints {
+		e.joints[joint] = true
+		joints[i] = ID(joint)
+		for r := range 4 {
+			for c := range 4 {
+				matrices = append(matrices, float64(geom.Skins.InverseBindMatrices[i][r][c]))
+			}
+		}
+	}
+
+	// each influence gets its own weight, normalized as COLLADA expects weights summing to 1
+	var (
+		weights []float64
+		vcount  []int
+		v       []int
+	)
+
+	for _, weight := range geom.SkinWeights {
+		sum := 0.0
+		for _, w := range weight.Weights {
+			sum += float64(w)
+		}
+
+		count := 0
+		for i, w := range weight.Weights {
+			if w == 0 {
+				continue
+			}
+			v = append(v, int(weight.Joints[i]), len(weights))
+			weights = append(weights, float64(w)/sum)
+			count++
+		}
+		vcount = append(vcount, count)
+	}
+
+	matricesSource := floatSource(id+"-bind-poses", matrices, "TRANSFORM")
+	matricesSource.Technique.Accessor.Params[0].Type = "float4x4"
+	matricesSource.Technique.Accessor.Count = len(joints)
+	matricesSource.Technique.Accessor.Stride = 16
+
+	skin.Sources = []Source{
+		nameSource(id+"-joints", joints, "JOINT"),
+		matricesSource,
+		floatSource(id+"-weights", weights, "WEIGHT"),
+	}
+
+	skin.Joints.Inputs = []Input{
+		{Semantic: "JOINT", Source: "#" + id + "-joints"},
+		{Semantic: "INV_BIND_MATRIX", Source: "#" + id + "-bind-poses"},
+	}
+
+	skin.VertexWeights = VertexWeights{
+		Count: len(geom.SkinWeights),
+		Inputs: []Input{
+			{Semantic: "JOINT", Source: "#" + id + "-joints", Offset: ptr(0)},
+			{Semantic: "WEIGHT", Source: "#" + id + "-weights", Offset: ptr(1)},
+		},
+		VCount: formatInts(vcount),
+		V:      formatInts(v),
+	}
+
+	e.doc.LibraryControllers.Controllers = append(e.doc.LibraryControllers.Controllers, Controller{
+		ID:   id,
+		Name: geom.Name,
+		Skin: skin,
+	})
+}
+
+func (e *exporter) exportCamera(cam *scw.Camera3D) {
+	if e.doc.LibraryCameras == nil {
+		e.doc.LibraryCameras = new(LibraryCameras)
+	}
+
+	perspective := &Perspective{
+		ZNear: cam.ZNear,
+		ZFar:  cam.ZFar,
+	}
+
+	// COLLADA accepts (xfov or yfov) and aspect_ratio, or both fovs
+	if cam.Xfov != 0 {
+		perspective.XFov = ptr(cam.Xfov)
+	}
+	if cam.Yfov != 0 {
+		perspective.YFov = ptr(cam.Yfov)
+	}
+	if cam.AspectRatio != 0 && (perspective.XFov == nil || perspective.YFov == nil) {
+		perspective.AspectRatio = ptr(cam.AspectRatio)
+	}
+
+	e.doc.LibraryCameras.Cameras = append(e.doc.LibraryCameras.Cameras, Camera{
+		ID:     ID(cam.Name) + "-camera",
+		Name:   cam.Name,
+		Optics: Optics{Technique: OpticsTechnique{Perspective: perspective}},
+	})
+}
+
+func (e *exporter) bindMaterial(instance *scw.NodeInstance) *BindMaterial {
+	if len(instance.Materials) == 0 {
+		return nil
+	}
+
+	bind := new(BindMaterial)
+	for _, mat := range instance.Materials {
+		bind.Technique.InstanceMaterials = append(bind.Technique.InstanceMaterials, InstanceMaterial{
+			Symbol: mat.Name,
+			Target: "#" + ID(mat.Target) + "-material",
+			BindVertexInputs: []BindVertexInput{
+				{Semantic: "TEXCOORD0", InputSemantic: "TEXCOORD", InputSet: 0},
+			},
+		})
+	}
+	return bind
+}
+
+// skeletonRoot returns the top most ancestor of the first joint of geom
+func (e *exporter) skeletonRoot(geom *scw.Geometry, parents map[string]string) string {
+	if len(geom.Skins.Joints) == 0 {
+		return ""
+	}
+
+	root := geom.Skins.Joints[0]
+	for seen := 0; seen < len(parents); seen++ {
+		parent, ok := parents[root]
+		if !ok || len(parent) == 0 {
+			break
+		}
+		root = parent
+	}
+	return root
+}
+
+func (e *exporter) findGeometry(name string) *scw.Geometry {
+	for _, geom := range e.file.Geometries {
+		if geom.Name == name {
+			return geom
+		}
+	}
+	return nil
+}
+
+func (e *exporter) exportNode(node *scw.Node, parents map[string]string) (Node, error) {
+	out := Node{
+		ID:   ID(node.Name),
+		SID:  ID(node.Name),
+		Name: node.Name,
+		Type: "NODE",
+	}
+
+	if e.joints[node.Name] {
+		out.Type = "JOINT"
+	}
+
+	if len(node.Frames) > 0 {
+		matrix := node.Frames[0].Matrix()
+		out.Transforms = []Transform{{
+			XMLName: xml.Name{Local: "matrix"},
+			SID:     "transform",
+			Data:    formatMatrix(&matrix),
+		}}
+	}
+
+	for i := range node.Instances {
+		instance := &node.Instances[i]
+
+		switch instance.Type {
+		case "GEOM":
+			out.InstanceGeometries = append(out.InstanceGeometries, InstanceGeometry{
+				URL:          "#" + ID(instance.Target) + "-mesh",
+				BindMaterial: e.bindMaterial(instance),
+			})
+		case "CONT":
+			controller := InstanceController{
+				URL:          "#" + ID(instance.Target) + "-skin",
+				BindMaterial: e.bindMaterial(instance),
+			}
+
+			geom := e.findGeometry(instance.Target)
+			if geom == nil {
+				return out, fmt.Errorf("node %s references unknown geometry: %s", node.Name, instance.Target)
+			}
+
+			if len(geom.Skins.Joints) == 0 {
+				// a controller without skin, nothing to bind
+				controller.URL = "#" + ID(instance.Target) + "-mesh"
+				out.InstanceGeometries = append(out.InstanceGeometries, InstanceGeometry{
+					URL:          controller.URL,
+					BindMaterial: controller.BindMaterial,
+				})
+				continue
+			}
+
+			if root := e.skeletonRoot(geom, parents); len(root) != 0 {
+				controller.Skeletons = []string{"#" + ID(root)}
+			}
+			out.InstanceControllers = append(out.InstanceControllers, controller)
+		case "CAME":
+			camera := InstanceCamera{URL: "#" + ID(instance.Target) + "-camera"}
+			if len(instance.CameraTarget) != 0 {
+				camera.Extra = &Extra{Technique: ExtraTechnique{Profile: extraProfile, Target: instance.CameraTarget}}
+			}
+			out.InstanceCameras = append(out.InstanceCameras, camera)
+		default:
+			return out, fmt.Errorf("node %s: unsupported instance type: %s", node.Name, instance.Type)
+		}
+	}
+
+	return out, nil
+}
+
+func (e *exporter) exportScene() error {
+	nodes := e.file.Nodes
+
+	parents := make(map[string]string)
+	for i := range nodes {
+		parents[nodes[i].Name] = nodes[i].ParentName
+	}
+
+	children := make(map[string][]int)
+	var roots []int
+
+	for i := range nodes {
+		if _, ok := parents[nodes[i].ParentName]; ok && len(nodes[i].ParentName) != 0 {
+			children[nodes[i].ParentName] = append(children[nodes[i].ParentName], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	var (
+		build   func(i int) (Node, error)
+		visited = make([]bool, len(nodes))
+	)
+
+	build = func(i int) (Node, error) {
+		visited[i] = true
+
+		out, err := e.exportNode(&nodes[i], parents)
+		if err != nil {
+			return out, err
+		}
+
+		for _, child := range children[nodes[i].Name] {
+			if visited[child] {
+				return out, fmt.Errorf("node %s is part of a parent cycle", nodes[child].Name)
+			}
+
+			node, err := build(child)
+			if err != nil {
+				return out, err
+			}
+			out.Nodes = append(out.Nodes, node)
+		}
+
+		return out, nil
+	}
+
+	scene := VisualScene{ID: "scene", Name: "scene"}
+
+	for _, root := range roots {
+		node, err := build(root)
+		if err != nil {
+			return err
+		}
+		scene.Nodes = append(scene.Nodes, node)
+	}
+
+	e.doc.LibraryVisualScenes = &LibraryVisualScenes{VisualScenes: []VisualScene{scene}}
+	e.doc.Scene = &Scene{InstanceVisualScene: InstanceURL{URL: "#scene"}}
+
+	return nil
+}
+
+// exportAnimations samples every animated node as a matrix animation of its transform
+func (e *exporter) exportAnimations() {
+	rate := float64(e.file.FrameRate)
+	if rate == 0 {
+		rate = scw.DefaultFrameRate
+	}
+
+	// files with both set to 0 have no range, so every frame is kept
+	ranged := e.file.FirstFrame != 0 || e.file.LastFrame != 0
+
+	for i := range e.file.Nodes {
+		node := &e.file.Nodes[i]
+
+		var (
+			times    []float64
+			matrices []float64
+		)
+
+		for f := range node.Frames {
+			frame := &node.Frames[f]
+			if ranged && (frame.ID < e.file.FirstFrame || frame.ID > e.file.LastFrame) {
+				continue
+			}
+
+			times = append(times, float64(int(frame.ID)-int(e.file.FirstFrame))/rate)
+			matrix := frame.Matrix()
+			for r := range 4 {
+				for c := range 4 {
+					matrices = append(matrices, float64(matrix[r][c]))
+				}
+			}
+		}
+
+		if len(times) < 2 {
+			continue
+		}
+
+		id := ID(node.Name) + "-anim"
+
+		interpolations := make([]string, len(times))
+		for f := range interpolations {
+			interpolations[f] = "LINEAR"
+		}
+
+		output := floatSource(id+"-output", matrices, "TRANSFORM")
+		output.Technique.Accessor.Params[0].Type = "float4x4"
+		output.Technique.Accessor.Count = len(times)
+		output.Technique.Accessor.Stride = 16
+
+		animation := Animation{
+			ID:   id,
+			Name: node.Name,
+			Sources: []Source{
+				floatSource(id+"-input", times, "TIME"),
+				output,
+				nameSource(id+"-interpolation", interpolations, "INTERPOLATION"),
+			},
+			Samplers: []Sampler{{
+				ID: id + "-sampler",
+				Inputs: []Input{
+					{Semantic: "INPUT", Source: "#" + id + "-input"},
+					{Semantic: "OUTPUT", Source: "#" + id + "-output"},
+					{Semantic: "INTERPOLATION", Source: "#" + id + "-interpolation"},
+				},
+			}},
+			Channels: []Channel{{
+				Source: "#" + id + "-sampler",
+				Target: ID(node.Name) + "/transform",
+			}},
+		}
+
+		if e.doc.LibraryAnimations == nil {
+			e.doc.LibraryAnimations = new(LibraryAnimations)
+		}
+		e.doc.LibraryAnimations.Animations = append(e.doc.LibraryAnimations.Animations, animation)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,153 @@
+package dae
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models/internal/modeltest"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"v0_minor0.scw", "v2.scw", "static.scw", "animated.scw"} {
+		t.Run(name, func(t *testing.T) {
+			file := modeltest.LoadFixture(t, name)
+
+			filename := filepath.Join(t.TempDir(), "model.dae")
+			if err := WriteFile(filename, file); err != nil {
+				t.Fatal(err)
+			}
+
+			imported, err := ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			modeltest.CompareGeometries(t, file, imported, 1e-5)
+			modeltest.CompareScene(t, file, imported)
+
+			// animations are sampled over the whole timeline, the key frames are among the samples
+			for i, node := range file.Nodes {
+				frames := make(map[uint16]scw.KeyFrame)
+				for _, frame := range imported.Nodes[i].Frames {
+					frames[frame.ID] = frame
+				}
+				for _, frame := range node.Frames {
+					if sampled, ok := frames[frame.ID]; !ok || !modeltest.SameRotation(sampled.Rotation, frame.Rotation) {
+						t.Fatalf("node %s: frame %+v imported as %+v", node.Name, frame, sampled)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestID(t *testing.T) {
+	tests := []struct {
+		name, expected string
+	}{
+		{"node", "node"},
+		{"red mat", "red_mat"},
+		{"a:b/c", "a_b_c"},
+		{"1st", "_1st"},
+		{"-node", "_-node"},
+		{".node", "_.node"},
+		{"nœud", "n_ud"},
+		{"", "_"},
+	}
+
+	for _, test := range tests {
+		if id := ID(test.name); id != test.expected {
+			t.Errorf("ID(%q) = %q, expected %q", test.name, id, test.expected)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	mat := &scw.Material{Name: "red mat"}
+	mat.Variables.Diffuse.Color = scw.RGBA{255, 0, 0, 255}
+
+	file := &scw.File{
+		Materials: []*scw.Material{mat},
+		Geometries: []*scw.Geometry{{
+			Name: "quad",
+			Vertices: []scw.SourceArray{
+				{Name: "POSITION", Index: 0, Stride: 3, Data: []float64{0, 0, 0, 1, 0, 0, 0, 1, 0}},
+				{Name: "TEXCOORD", Index: 1, Stride: 2, Data: []float64{0, 0}},
+			},
+			Materials: []scw.IndexArray{{Name: "sym", InputsCount: 2, TrianglesCount: 1, IndexBuffer: []uint32{0, 0, 1, 0, 2, 0}}},
+		}},
+		Cameras: []*scw.Camera3D{{Name: "cam", Yfov: 45, AspectRatio: 1.5, ZNear: 0.1, ZFar: 100}},
+		Scene: scw.Scene{Nodes: []scw.Node{
+			{Name: "root", Instances: []scw.NodeInstance{{Type: "CAME", Target: "cam", CameraTarget: "child"}}},
+			{
+				Name:       "child",
+				ParentName: "root",
+				Instances:  []scw.NodeInstance{{Type: "GEOM", Target: "quad", Materials: []scw.InstanceMaterial{{Name: "sym", Target: "red mat"}}}},
+			},
+		}},
+	}
+
+	doc, err := Export(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	material := doc.LibraryMaterials.Materials[0]
+	effect := doc.LibraryEffects.Effects[0]
+	if material.ID != "red_mat-material" || material.InstanceEffect.URL != "#"+effect.ID || effect.ID != "red_mat-effect" {
+		t.Errorf("material %+v with effect %s, expected red_mat-material using red_mat-effect", material, effect.ID)
+	}
+
+	if diffuse := effect.Profile.Technique.Phong.Diffuse; diffuse.Color == nil || diffuse.Color.Data != "1 0 0 1" {
+		t.Errorf("diffuse = %+v, expected the color 1 0 0 1", diffuse)
+	}
+
+	geom := doc.LibraryGeometries.Geometries[0]
+	if geom.ID != "quad-mesh" || len(geom.Mesh.Sources) != 2 || len(geom.Mesh.Triangles) != 1 {
+		t.Fatalf("geometry %s has %d sources and %d primitives, expected quad-mesh with 2 and 1", geom.ID, len(geom.Mesh.Sources), len(geom.Mesh.Triangles))
+	}
+
+	triangles := geom.Mesh.Triangles[0]
+	if triangles.Material != "sym" || triangles.Count != 1 || triangles.P != "0 0 1 0 2 0" {
+		t.Errorf("triangles = %+v, expected 1 triangle of sym with the scw indices", triangles)
+	}
+
+	// the position goes through <vertices>, every source reads its index at its own offset
+	inputs := triangles.Inputs
+	if len(inputs) != 2 || inputs[0].Semantic != "VERTEX" || inputs[0].Source != "#"+geom.Mesh.Vertices.ID || *inputs[0].Offset != 0 ||
+		inputs[1].Semantic != "TEXCOORD" || *inputs[1].Offset != 1 || inputs[1].Set == nil || *inputs[1].Set != 0 {
+		t.Errorf("inputs = %+v, expected VERTEX at offset 0 and TEXCOORD set 0 at offset 1", inputs)
+	}
+
+	camera := doc.LibraryCameras.Cameras[0]
+	perspective := camera.Optics.Technique.Perspective
+	if camera.ID != "cam-camera" || perspective.YFov == nil || *perspective.YFov != 45 || perspective.XFov != nil ||
+		perspective.AspectRatio == nil || *perspective.AspectRatio != 1.5 {
+		t.Errorf("camera %s = %+v, expected cam-camera with yfov 45 and aspect ratio 1.5", camera.ID, perspective)
+	}
+
+	if doc.Scene == nil || doc.Scene.InstanceVisualScene.URL != "#scene" {
+		t.Fatalf("scene = %+v, expected an instance of #scene", doc.Scene)
+	}
+
+	roots := doc.LibraryVisualScenes.VisualScenes[0].Nodes
+	if len(roots) != 1 || roots[0].ID != "root" || len(roots[0].Nodes) != 1 || roots[0].Nodes[0].ID != "child" {
+		t.Fatalf("visual scene = %+v, expected child nested in root", roots)
+	}
+
+	cameras := roots[0].InstanceCameras
+	if len(cameras) != 1 || cameras[0].URL != "#cam-camera" || cameras[0].Extra == nil || cameras[0].Extra.Technique.Target != "child" {
+		t.Errorf("root cameras = %+v, expected #cam-camera targeting child", cameras)
+	}
+
+	instances := roots[0].Nodes[0].InstanceGeometries
+	if len(instances) != 1 || instances[0].URL != "#quad-mesh" || instances[0].BindMaterial == nil {
+		t.Fatalf("child geometries = %+v, expected #quad-mesh with a material binding", instances)
+	}
+
+	bindings := instances[0].BindMaterial.Technique.InstanceMaterials
+	if len(bindings) != 1 || bindings[0].Symbol != "sym" || bindings[0].Target != "#red_mat-material" {
+		t.Errorf("bindings = %+v, expected sym bound to #red_mat-material", bindings)
+	}
+}
@@ -0,0 +1,1310 @@
+package dae
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+type importer struct {
+	doc  *Collada
+	file *scw.File
+
+	effects   map[string]*Effect
+	images    map[string]*Image
+	materials map[string]string // material id -> scw material
+	cameras   map[string]string // camera id -> scw camera
+
+	nodes     []*sceneNode
+	nodesByID map[string]*sceneNode
+	nodeNames map[string]string // node id or sid -> scw node, used to resolve joints
+
+	geometries    map[[2]string]*scw.Geometry // (geometry id, controller id) -> geometry
+	geometryNames map[string]bool
+}
+
+// sceneNode is a visual scene node with its parsed transformations
+type sceneNode struct {
+	node       *Node
+	name       string
+	parent     *sceneNode
+	transforms []transform
+	index      int // scw node
+}
+
+// transform is a transformation element of a node (matrix, translate, rotate or scale)
+type transform struct {
+	kind   string
+	sid    string
+	values []float64
+}
+
+// IsCollada reports if data looks like a COLLADA document, the root element is searched
+// in the first kilobyte so the xml declaration and comments before it are skipped
+func IsCollada(data []byte) bool {
+	return bytes.Contains(data[:min(len(data), 1024)], []byte("<COLLADA"))
+}
+
+// ReadFile imports a .dae file, texture paths are kept relative to it
+func ReadFile(filename string) (*scw.File, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Import(data, filepath.Dir(filename))
+}
+
+// Import builds a version 2 scw File from a COLLADA document
+//
+// only the instanced visual scene is imported, animations are sampled at the file frame rate
+// since scw key frames hold translation, rotation and scale
+//
+// dir is not used as images are only referenced by path, it matches the other importers
+func Import(data []byte, dir string) (*scw.File, error) {
+	doc := new(Collada)
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+
+	file := &scw.File{}
+	file.Header = scw.Header{
+		Version:   2,
+		FrameRate: scw.DefaultFrameRate,
+	}
+
+	im := &importer{
+		doc:           doc,
+		file:          file,
+		effects:       make(map[string]*Effect),
+		images:        make(map[string]*Image),
+		materials:     make(map[string]string),
+		cameras:       make(map[string]string),
+		nodesByID:     make(map[string]*sceneNode),
+		nodeNames:     make(map[string]string),
+		geometries:    make(map[[2]string]*scw.Geometry),
+		geometryNames: make(map[string]bool),
+	}
+
+	im.importMaterials()
+	im.importCameras()
+
+	if err := im.importScene(); err != nil {
+		return nil, err
+	}
+
+	if err := im.importAnimations(); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// fragment returns the id referenced by a local url (#id)
+func fragment(ref string) string {
+	return strings.TrimPrefix(ref, "#")
+}
+
+func parseFloats(data string) ([]float64, error) {
+	fields := strings.Fields(data)
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func parseInts(data string) ([]int, error) {
+	fields := strings.Fields(data)
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// parseMatrix reads a row major COLLADA matrix
+func parseMatrix(data string) (scw.Matrix4x4, error) {
+	var m scw.Matrix4x4
+
+	values, err := parseFloats(data)
+	if err != nil {
+		return m, err
+	}
+	if len(values) != 16 {
+		return m, fmt.Errorf("matrix has %d values", len(values))
+	}
+
+	for r := range 4 {
+		for c := range 4 {
+			m[r][c] = float32(values[r*4+c])
+		}
+	}
+	return m, nil
+}
+
+// sourceValues returns the float values of a source and its stride
+func sourceValues(src *Source) ([]float64, int, error) {
+	if src.FloatArray == nil {
+		return nil, 0, fmt.Errorf("source %s has no float_array", src.ID)
+	}
+
+	values, err := parseFloats(src.FloatArray.Data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("source %s: %w", src.ID, err)
+	}
+
+	stride := 1
+	if src.Technique != nil {
+		accessor := &src.Technique.Accessor
+		stride = max(accessor.Stride, 1)
+		if n := accessor.Count * stride; n < len(values) {
+			values = values[:n]
+		}
+	}
+
+	return values, stride, nil
+}
+
+// sourceNames returns the names of a Name_array or IDREF_array source
+func sourceNames(src *Source) ([]string, error) {
+	switch {
+	case src.NameArray != nil:
+		return strings.Fields(src.NameArray.Data), nil
+	case src.IDREFArray != nil:
+		return strings.Fields(src.IDREFArray.Data), nil
+	default:
+		return nil, fmt.Errorf("source %s has no Name_array or IDREF_array", src.ID)
+	}
+}
+
+func findSource(sources []Source, ref string) *Source {
+	id := fragment(ref)
+	for i := range sources {
+		if sources[i].ID == id {
+			return &sources[i]
+		}
+	}
+	return nil
+}
+
+// texturePath follows a texture through the sampler and surface params of the effect to its image
+func (im *importer) texturePath(effect *Effect, texture string) string {
+	params := make(map[string]*NewParam)
+	for i := range effect.Profile.NewParams {
+		params[effect.Profile.NewParams[i].SID] = &effect.Profile.NewParams[i]
+	}
+
+	image := texture
+	if param, ok := params[texture]; ok && param.Sampler2D != nil {
+		image = param.Sampler2D.Source
+		if param, ok = params[image]; ok && param.Surface != nil {
+			image = param.Surface.InitFrom
+		}
+	}
+
+	path := image
+	if img, ok := im.images[image]; ok {
+		path = img.InitFrom
+	}
+
+	path = strings.TrimPrefix(strings.TrimSpace(path), "file://")
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		return unescaped
+	}
+	return path
+}
+
+func (im *importer) variable(effect *Effect, value *ColorOrTexture, variable *scw.Variable) {
+	switch {
+	case value == nil:
+	case value.Texture != nil:
+		variable.UseText2D = true
+		variable.Texture2D = im.texturePath(effect, value.Texture.Texture)
+	case value.Color != nil:
+		color, err := parseFloats(value.Color.Data)
+		if err == nil {
+			variable.Color = scw.RGBAFromFloats(color)
+		}
+	}
+}
+
+func (im *importer) importMaterials() {
+	if im.doc.LibraryImages != nil {
+		for i := range im.doc.LibraryImages.Images {
+			img := &im.doc.LibraryImages.Images[i]
+			im.images[img.ID] = img
+		}
+	}
+
+	if im.doc.LibraryEffects != nil {
+		for i := range im.doc.LibraryEffects.Effects {
+			effect := &im.doc.LibraryEffects.Effects[i]
+			im.effects[effect.ID] = effect
+		}
+	}
+
+	if im.doc.LibraryMaterials == nil {
+		return
+	}
+
+	used := make(map[string]bool)
+
+	for i := range im.doc.LibraryMaterials.Materials {
+		mat := &im.doc.LibraryMaterials.Materials[i]
+
+		name := mat.Name
+		if len(name) == 0 {
+			name = mat.ID
+		}
+
+		m := &scw.Material{
+			SCWFile: im.file,
+			Name:    scw.UniqueName(name, fmt.Sprintf("material_%d", i), used),
+		}
+		im.materials[mat.ID] = m.Name
+
+		vars := &m.Variables
+		vars.Opacity = 1
+		vars.Diffuse.Color = scw.RGBA{255, 255, 255, 255}
+
+		effect, ok := im.effects[fragment(mat.InstanceEffect.URL)]
+		if ok {
+			technique := &effect.Profile.Technique
+
+			shading := technique.Phong
+			if shading == nil {
+				shading = technique.Blinn
+			}
+			if shading == nil {
+				shading = technique.Lambert
+			}
+
+			if shading != nil {
+				im.variable(effect, shading.Emission, &vars.Emission)
+				im.variable(effect, shading.Ambient, &vars.Ambient)
+				im.variable(effect, shading.Diffuse, &vars.Diffuse)
+				im.variable(effect, shading.Specular, &vars.Specular)
+
+				if shading.Transparency != nil {
+					vars.Opacity = float32(shading.Transparency.Float)
+				}
+
+				if transparent := shading.Transparent; transparent != nil {
+					if transparent.Texture != nil {
+						vars.OpacityTex2D = im.texturePath(effect, transparent.Texture.Texture)
+					} else if transparent.Color != nil {
+						// A_ONE, the default opaque mode, scales the transparency with the color alpha
+						if color, err := parseFloats(transparent.Color.Data); err == nil && len(color) == 4 {
+							vars.Opacity *= float32(color[3])
+						}
+					}
+				}
+			}
+		}
+
+		im.file.Materials = append(im.file.Materials, m)
+	}
+}
+
+func (im *importer) importCameras() {
+	if im.doc.LibraryCameras == nil {
+		return
+	}
+
+	used := make(map[string]bool)
+
+	for i := range im.doc.LibraryCameras.Cameras {
+		cam := &im.doc.LibraryCameras.Cameras[i]
+
+		// scw only has perspective cameras
+		p := cam.Optics.Technique.Perspective
+		if p == nil {
+			continue
+		}
+
+		name := cam.Name
+		if len(name) == 0 {
+			name = cam.ID
+		}
+
+		camera := &scw.Camera3D{
+			Name:  scw.UniqueName(name, fmt.Sprintf("camera_%d", i), used),
+			ZNear: p.ZNear,
+			ZFar:  p.ZFar,
+		}
+
+		if p.AspectRatio != nil {
+			camera.AspectRatio = *p.AspectRatio
+		}
+
+		// the missing field of view is derived from the other one and the aspect ratio (width / height)
+		tan := func(fov float32) float64 { return math.Tan(float64(fov) * math.Pi / 360) }
+		atan := func(v float64) float32 { return float32(math.Atan(v) * 360 / math.Pi) }
+
+		switch {
+		case p.XFov != nil && p.YFov != nil:
+			camera.Xfov, camera.Yfov = *p.XFov, *p.YFov
+			if camera.AspectRatio == 0 && tan(camera.Yfov) != 0 {
+				camera.AspectRatio = float32(tan(camera.Xfov) / tan(camera.Yfov))
+			}
+		case p.XFov != nil:
+			camera.Xfov = *p.XFov
+			if camera.AspectRatio != 0 {
+				camera.Yfov = atan(tan(camera.Xfov) / float64(camera.AspectRatio))
+			}
+		case p.YFov != nil:
+			camera.Yfov = *p.YFov
+			if camera.AspectRatio != 0 {
+				camera.Xfov = atan(tan(camera.Yfov) * float64(camera.AspectRatio))
+			}
+		}
+
+		im.cameras[cam.ID] = camera.Name
+		im.file.Cameras = append(im.file.Cameras, camera)
+	}
+}
+
+// matrix returns the transformation as a matrix
+func (t *transform) matrix() (scw.Matrix4x4, error) {
+	frame := scw.KeyFrame{
+		Rotation: scw.Quaternion{W: 1},
+		Scale:    scw.Vector3{X: 1, Y: 1, Z: 1},
+	}
+
+	expected := map[string]int{"matrix": 16, "translate": 3, "rotate": 4, "scale": 3}[t.kind]
+	if len(t.values) != expected {
+		return scw.Matrix4x4{}, fmt.Errorf("%s has %d values", t.kind, len(t.values))
+	}
+
+	v := t.values
+
+	switch t.kind {
+	case "matrix":
+		var m scw.Matrix4x4
+		for r := range 4 {
+			for c := range 4 {
+				m[r][c] = float32(v[r*4+c])
+			}
+		}
+		return m, nil
+	case "translate":
+		frame.Translation = scw.Vector3{X: float32(v[0]), Y: float32(v[1]), Z: float32(v[2])}
+	case "scale":
+		frame.Scale = scw.Vector3{X: float32(v[0]), Y: float32(v[1]), Z: float32(v[2])}
+	case "rotate":
+		// axis and angle in degrees
+		length := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+		if length == 0 {
+			break
+		}
+		half := v[3] * math.Pi / 360
+		sin := math.Sin(half) / length
+		frame.Rotation = scw.Quaternion{
+			Vector3: scw.Vector3{X: float32(v[0] * sin), Y: float32(v[1] * sin), Z: float32(v[2] * sin)},
+			W:       float32(math.Cos(half)),
+		}
+	}
+
+	return frame.Matrix(), nil
+}
+
+// keyFrame composes the transformations in order and splits the result in a key frame
+func keyFrame(transforms []transform, id uint16) (scw.KeyFrame, error) {
+	m := scw.Identity()
+	for i := range transforms {
+		t, err := transforms[i].matrix()
+		if err != nil {
+			return scw.KeyFrame{}, err
+		}
+		m = m.Multiply(&t)
+	}
+
+	frame := scw.KeyFrame{ID: id}
+	frame.Translation, frame.Rotation, frame.Scale = m.Decompose()
+	return frame, nil
+}
+
+func parseTransforms(node *Node) ([]transform, error) {
+	var transforms []transform
+
+	for _, t := range node.Transforms {
+		switch kind := t.XMLName.Local; kind {
+		case "matrix", "translate", "rotate", "scale":
+			values, err := parseFloats(t.Data)
+			if err != nil {
+				return nil, err
+			}
+			transforms = append(transforms, transform{kind: kind, sid: t.SID, values: values})
+		case "lookat", "skew":
+			return nil, fmt.Errorf("unsupported transform: %s", kind)
+		default:
+			// not a transformation (instance_light, instance_node, extra...)
+		}
+	}
+
+	return transforms, nil
+}
+
+func (im *importer) visualScene() *VisualScene {
+	if im.doc.LibraryVisualScenes == nil || len(im.doc.LibraryVisualScenes.VisualScenes) == 0 {
+		return nil
+	}
+
+	scenes := im.doc.LibraryVisualScenes.VisualScenes
+	if im.doc.Scene != nil {
+		id := fragment(im.doc.Scene.InstanceVisualScene.URL)
+		for i := range scenes {
+			if scenes[i].ID == id {
+				return &scenes[i]
+			}
+		}
+	}
+
+	return &scenes[0]
+}
+
+func (im *importer) importScene() error {
+	scene := im.visualScene()
+	if scene == nil {
+		return nil
+	}
+
+	used := make(map[string]bool)
+
+	// every node is named first so skin joints can be resolved while importing instances,
+	// parents are listed before their children
+	var collect func(nodes []Node, parent *sceneNode) error
+	collect = func(nodes []Node, parent *sceneNode) error {
+		for i := range nodes {
+			node := &nodes[i]
+
+			name := node.Name
+			if len(name) == 0 {
+				name = node.ID
+			}
+
+			sn := &sceneNode{
+				node:   node,
+				name:   scw.UniqueName(name, fmt.Sprintf("node_%d", len(im.nodes)), used),
+				parent: parent,
+			}
+
+			var err error
+			if sn.transforms, err = parseTransforms(node); err != nil {
+				return fmt.Errorf("node %s: %w", sn.name, err)
+			}
+
+			im.nodes = append(im.nodes, sn)
+			if len(node.ID) != 0 {
+				im.nodesByID[node.ID] = sn
+				im.nodeNames[node.ID] = sn.name
+			}
+			if _, ok := im.nodeNames[node.SID]; len(node.SID) != 0 && !ok {
+				im.nodeNames[node.SID] = sn.name
+			}
+
+			if err = collect(node.Nodes, sn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := collect(scene.Nodes, nil); err != nil {
+		return err
+	}
+
+	for _, sn := range im.nodes {
+		node := scw.Node{
+			SCWFile: im.file,
+			Name:    sn.name,
+		}
+
+		if sn.parent != nil {
+			node.ParentName = sn.parent.name
+		}
+
+		rest, err := keyFrame(sn.transforms, 0)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", sn.name, err)
+		}
+		node.Frames = []scw.KeyFrame{rest}
+
+		if err = im.importInstances(sn.node, &node); err != nil {
+			return err
+		}
+
+		sn.index = len(im.file.Nodes)
+		im.file.Nodes = append(im.file.Nodes, node)
+	}
+
+	return nil
+}
+
+func (im *importer) bindMaterial(bind *BindMaterial, instance *scw.NodeInstance) error {
+	if bind == nil {
+		return nil
+	}
+
+	for _, mat := range bind.Technique.InstanceMaterials {
+		target, ok := im.materials[fragment(mat.Target)]
+		if !ok {
+			return fmt.Errorf("instance of %s binds unknown material: %s", instance.Target, mat.Target)
+		}
+		instance.Materials = append(instance.Materials, scw.InstanceMaterial{Name: mat.Symbol, Target: target})
+	}
+
+	return nil
+}
+
+func (im *importer) importInstances(in *Node, node *scw.Node) error {
+	for _, instance := range in.InstanceGeometries {
+		geom, err := im.geometry(fragment(instance.URL), "")
+		if err != nil {
+			return fmt.Errorf("node %s: %w", node.Name, err)
+		}
+
+		out := scw.NodeInstance{Type: "GEOM", Target: geom.Name}
+		if err = im.bindMaterial(instance.BindMaterial, &out); err != nil {
+			return fmt.Errorf("node %s: %w", node.Name, err)
+		}
+		node.Instances = append(node.Instances, out)
+	}
+
+	for _, instance := range in.InstanceControllers {
+		controller := im.controller(fragment(instance.URL))
+		if controller == nil || controller.Skin == nil {
+			return fmt.Errorf("node %s: unsupported controller: %s", node.Name, instance.URL)
+		}
+
+		geom, err := im.geometry(fragment(controller.Skin.Source), controller.ID)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", node.Name, err)
+		}
+
+		out := scw.NodeInstance{Type: "CONT", Target: geom.Name}
+		if err = im.bindMaterial(instance.BindMaterial, &out); err != nil {
+			return fmt.Errorf("node %s: %w", node.Name, err)
+		}
+		node.Instances = append(node.Instances, out)
+	}
+
+	for _, instance := range in.InstanceCameras {
+		target, ok := im.cameras[fragment(instance.URL)]
+		if !ok {
+			// orthographic cameras are not imported
+			continue
+		}
+
+		out := scw.NodeInstance{Type: "CAME", Target: target}
+		if extra := instance.Extra; extra != nil && extra.Technique.Profile == extraProfile {
+			out.CameraTarget = extra.Technique.Target
+		}
+		node.Instances = append(node.Instances, out)
+	}
+
+	return nil
+}
+
+func (im *importer) controller(id string) *Controller {
+	if im.doc.LibraryControllers == nil {
+		return nil
+	}
+
+	for i := range im.doc.LibraryControllers.Controllers {
+		if controller := &im.doc.LibraryControllers.Controllers[i]; controller.ID == id {
+			return controller
+		}
+	}
+	return nil
+}
+
+func (im *importer) geometry(id, controller string) (*scw.Geometry, error) {
+	key := [2]string{id, controller}
+	if geom, ok := im.geometries[key]; ok {
+		return geom, nil
+	}
+
+	var g *Geometry
+	if im.doc.LibraryGeometries != nil {
+		for i := range im.doc.LibraryGeometries.Geometries {
+			if im.doc.LibraryGeometries.Geometries[i].ID == id {
+				g = &im.doc.LibraryGeometries.Geometries[i]
+			}
+		}
+	}
+
+	if g == nil {
+		return nil, fmt.Errorf("unknown geometry: %s", id)
+	}
+
+	name := g.Name
+	if len(name) == 0 {
+		name = g.ID
+	}
+	name = scw.UniqueName(name, fmt.Sprintf("mesh_%d", len(im.geometries)), im.geometryNames)
+
+	geom, err := im.importGeometry(g, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(controller) != 0 {
+		if err = im.importSkin(geom, im.controller(controller).Skin); err != nil {
+			return nil, err
+		}
+	}
+
+	im.geometries[key] = geom
+	im.file.Geometries = append(im.file.Geometries, geom)
+	return geom, nil
+}
+
+// input is a primitive input with <vertices> expanded
+type input struct {
+	semantic string
+	source   string
+	offset   int
+	set      int
+}
+
+func (im *importer) primitiveInputs(mesh *Mesh, prim *Primitive) []input {
+	var inputs []input
+
+	for _, in := range prim.Inputs {
+		offset, set := 0, 0
+		if in.Offset != nil {
+			offset = *in.Offset
+		}
+		if in.Set != nil {
+			set = *in.Set
+		}
+
+		if in.Semantic != "VERTEX" {
+			inputs = append(inputs, input{in.Semantic, fragment(in.Source), offset, set})
+			continue
+		}
+
+		for _, vertex := range mesh.Vertices.Inputs {
+			inputs = append(inputs, input{vertex.Semantic, fragment(vertex.Source), offset, set})
+		}
+	}
+
+	return inputs
+}
+
+// primitiveTriangles returns the indices of a triangles or polylist element as triangles,
+// polygons are triangulated as fans
+func primitiveTriangles(prim *Primitive, stride int) ([]uint32, error) {
+	p, err := parseInts(prim.P)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, idx := range p {
+		if idx < 0 {
+			return nil, fmt.Errorf("negative index: %d", idx)
+		}
+	}
+
+	if len(prim.VCount) == 0 {
+		if len(p) != 3*prim.Count*stride {
+			return nil, fmt.Errorf("triangles have %d indices, %d expected", len(p), 3*prim.Count*stride)
+		}
+
+		out := make([]uint32, len(p))
+		for i, idx := range p {
+			out[i] = uint32(idx)
+		}
+		return out, nil
+	}
+
+	vcount, err := parseInts(prim.VCount)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out  []uint32
+		base int
+	)
+
+	corner := func(i int) []int {
+		return p[(base+i)*stride : (base+i+1)*stride]
+	}
+
+	for _, n := range vcount {
+		if n < 0 || (base+n)*stride > len(p) {
+			return nil, fmt.Errorf("polylist vcount does not match its indices")
+		}
+
+		for i := 1; i+1 < n; i++ {
+			for _, c := range [3]int{0, i, i + 1} {
+				for _, idx := range corner(c) {
+					out = append(out, uint32(idx))
+				}
+			}
+		}
+		base += n
+	}
+
+	return out, nil
+}
+
+func (im *importer) importGeometry(g *Geometry, name string) (*scw.Geometry, error) {
+	mesh := g.Mesh
+	if mesh == nil {
+		return nil, fmt.Errorf("geometry %s is not a mesh", name)
+	}
+
+	prims := append(slices.Clone(mesh.Triangles), mesh.Polylist...)
+	if len(prims) == 0 {
+		return nil, fmt.Errorf("geometry %s has no triangles or polylist", name)
+	}
+
+	// scw index arrays share the sources of their geometry, so every primitive must use the same inputs
+	inputs := im.primitiveInputs(mesh, &prims[0])
+	for p := 1; p < len(prims); p++ {
+		if !slices.Equal(inputs, im.primitiveInputs(mesh, &prims[p])) {
+			return nil, fmt.Errorf("geometry %s: primitives have different inputs", name)
+		}
+	}
+
+	geom := &scw.Geometry{
+		SCWFile: im.file,
+		Name:    name,
+	}
+
+	stride := 0
+	hasPosition := false
+
+	for _, in := range inputs {
+		src := findSource(mesh.Sources, in.source)
+		if src == nil {
+			return nil, fmt.Errorf("geometry %s: unknown source: %s", name, in.source)
+		}
+
+		values, components, err := sourceValues(src)
+		if err != nil {
+			return nil, fmt.Errorf("geometry %s: %w", name, err)
+		}
+
+		if in.offset < 0 || in.set < 0 {
+			return nil, fmt.Errorf("geometry %s: %s input has a negative offset or set", name, in.semantic)
+		}
+
+		if in.offset > math.MaxUint8 || in.set > math.MaxUint8 || components > math.MaxUint8 {
+			return nil, fmt.Errorf("geometry %s: %s input does not fit in scw", name, in.semantic)
+		}
+
+		hasPosition = hasPosition || in.semantic == "POSITION"
+		stride = max(stride, in.offset+1)
+
+		geom.Vertices = append(geom.Vertices, scw.SourceArray{
+			Name:        in.semantic,
+			Index:       byte(in.offset),
+			SourceIndex: byte(in.set),
+			Stride:      byte(components),
+			Scale:       scw.QuantizationScale(values),
+			Data:        values,
+		})
+	}
+
+	if !hasPosition {
+		return nil, fmt.Errorf("geometry %s has no POSITION input", name)
+	}
+
+	maxIndex := uint32(0)
+
+	for p := range prims {
+		indices, err := primitiveTriangles(&prims[p], stride)
+		if err != nil {
+			return nil, fmt.Errorf("geometry %s: %w", name, err)
+		}
+
+		for _, idx := range indices {
+			maxIndex = max(maxIndex, idx)
+		}
+
+		geom.Materials = append(geom.Materials, scw.IndexArray{
+			Name:           prims[p].Material,
+			IndexBuffer:    indices,
+			TrianglesCount: uint32(len(indices) / (3 * stride)),
+			InputsCount:    byte(stride),
+		})
+	}
+
+	indexBufferSize := byte(4)
+	if maxIndex <= math.MaxUint8 {
+		indexBufferSize = 1
+	} else if maxIndex <= math.MaxUint16 {
+		indexBufferSize = 2
+	}
+
+	for i := range geom.Materials {
+		geom.Materials[i].IndexBufferSize = indexBufferSize
+	}
+
+	return geom, nil
+}
+
+// importSkin reads the joints, bind matrices and weights of a skin controller,
+// scw only keeps the 4 strongest influences of each position
+func (im *importer) importSkin(geom *scw.Geometry, skin *Skin) error {
+	geom.HasBindMatrix = true
+	geom.BindMatrix = scw.Identity()
+
+	if len(strings.TrimSpace(skin.BindShapeMatrix)) != 0 {
+		matrix, err := parseMatrix(skin.BindShapeMatrix)
+		if err != nil {
+			return fmt.Errorf("skin of %s: %w", geom.Name, err)
+		}
+		geom.BindMatrix = matrix
+	}
+
+	var (
+		joints   []string
+		matrices []float64
+	)
+
+	for _, in := range skin.Joints.Inputs {
+		src := findSource(skin.Sources, in.Source)
+		if src == nil {
+			return fmt.Errorf("skin of %s: unknown source: %s", geom.Name, in.Source)
+		}
+
+		var err error
+		switch in.Semantic {
+		case "JOINT":
+			joints, err = sourceNames(src)
+		case "INV_BIND_MATRIX":
+			matrices, _, err = sourceValues(src)
+		}
+		if err != nil {
+			return fmt.Errorf("skin of %s: %w", geom.Name, err)
+		}
+	}
+
+	if matrices != nil && len(matrices) != 16*len(joints) {
+		return fmt.Errorf("skin of %s: inverse bind matrices do not match its joints", geom.Name)
+	}
+
+	for i, joint := range joints {
+		name, ok := im.nodeNames[joint]
+		if !ok {
+			return fmt.Errorf("skin of %s has an unknown joint: %s", geom.Name, joint)
+		}
+		geom.Skins.Joints = append(geom.Skins.Joints, name)
+
+		matrix := scw.Identity()
+		if matrices != nil {
+			for r := range 4 {
+				for c := range 4 {
+					matrix[r][c] = float32(matrices[i*16+r*4+c])
+				}
+			}
+		}
+		geom.Skins.InverseBindMatrices = append(geom.Skins.InverseBindMatrices, matrix)
+	}
+
+	vw := &skin.VertexWeights
+
+	jointOffset, weightOffset, stride := -1, -1, 0
+	var weights []float64
+
+	for _, in := range vw.Inputs {
+		offset := 0
+		if in.Offset != nil {
+			offset = *in.Offset
+		}
+		if offset < 0 {
+			return fmt.Errorf("skin of %s: %s input has a negative offset", geom.Name, in.Semantic)
+		}
+		stride = max(stride, offset+1)
+
+		switch in.Semantic {
+		case "JOINT":
+			jointOffset = offset
+		case "WEIGHT":
+			weightOffset = offset
+
+			src := findSource(skin.Sources, in.Source)
+			if src == nil {
+				return fmt.Errorf("skin of %s: unknown source: %s", geom.Name, in.Source)
+			}
+
+			var err error
+			if weights, _, err = sourceValues(src); err != nil {
+				return fmt.Errorf("skin of %s: %w", geom.Name, err)
+			}
+		}
+	}
+
+	if jointOffset == -1 || weightOffset == -1 {
+		return fmt.Errorf("skin of %s has no JOINT or WEIGHT vertex weights", geom.Name)
+	}
+
+	vcount, err := parseInts(vw.VCount)
+	if err != nil {
+		return fmt.Errorf("skin of %s: %w", geom.Name, err)
+	}
+
+	v, err := parseInts(vw.V)
+	if err != nil {
+		return fmt.Errorf("skin of %s: %w", geom.Name, err)
+	}
+
+	type influence struct {
+		joint  int
+		weight float64
+	}
+
+	base := 0
+	for _, n := range vcount {
+		if n < 0 || (base+n)*stride > len(v) {
+			return fmt.Errorf("skin of %s: vertex weights vcount does not match its indices", geom.Name)
+		}
+
+		var influences []influence
+		for i := range n {
+			joint, weight := v[(base+i)*stride+jointOffset], v[(base+i)*stride+weightOffset]
+			if weight < 0 || weight >= len(weights) {
+				return fmt.Errorf("skin of %s has an invalid weight index: %d", geom.Name, weight)
+			}
+
+			// -1 binds to the bind shape, which has no scw equivalent
+			if joint < 0 {
+				continue
+			}
+
+			if joint >= len(joints) || joint > math.MaxUint8 {
+				return fmt.Errorf("skin of %s has an invalid joint index: %d", geom.Name, joint)
+			}
+
+			influences = append(influences, influence{joint, weights[weight]})
+		}
+		base += n
+
+		sort.SliceStable(influences, func(i, j int) bool {
+			return influences[i].weight > influences[j].weight
+		})
+		influences = influences[:min(len(influences), 4)]
+
+		sum := 0.0
+		for _, in := range influences {
+			sum += in.weight
+		}
+
+		var weight scw.Weight
+		for i, in := range influences {
+			weight.Joints[i] = byte(in.joint)
+			if sum > 0 {
+				weight.Weights[i] = uint16(math.Round(in.weight / sum * math.MaxUint16))
+			}
+		}
+
+		geom.SkinWeights = append(geom.SkinWeights, weight)
+	}
+
+	return nil
+}
+
+// channel is an animated transformation (or a single value of it) of a node
+type channel struct {
+	node      *sceneNode
+	transform int
+	index     int // -1 when the whole transformation is animated
+
+	times      []float64
+	values     []float64
+	components int
+	step       bool
+}
+
+// sample evaluates the channel at time, bezier and hermite curves are approximated linearly
+func (c *channel) sample(time float64, out []float64) {
+	value := func(k int) []float64 {
+		return c.values[k*c.components : (k+1)*c.components]
+	}
+
+	last := len(c.times) - 1
+
+	if time <= c.times[0] {
+		copy(out, value(0))
+		return
+	}
+
+	if time >= c.times[last] {
+		copy(out, value(last))
+		return
+	}
+
+	k := sort.SearchFloat64s(c.times, time)
+	if c.times[k] == time {
+		copy(out, value(k))
+		return
+	}
+	k-- // times[k] < time < times[k+1]
+
+	if c.step {
+		copy(out, value(k))
+		return
+	}
+
+	f := (time - c.times[k]) / (c.times[k+1] - c.times[k])
+	a, b := value(k), value(k+1)
+	for i := range out {
+		out[i] = a[i] + (b[i]-a[i])*f
+	}
+}
+
+// memberIndex returns the value targeted by a member selector (.X, .ANGLE...) or array index ((i) or (row)(column))
+func memberIndex(kind, selector string) (int, error) {
+	if member, ok := strings.CutPrefix(selector, "."); ok {
+		switch member {
+		case "X":
+			return 0, nil
+		case "Y":
+			return 1, nil
+		case "Z":
+			return 2, nil
+		case "ANGLE":
+			if kind == "rotate" {
+				return 3, nil
+			}
+		}
+		return 0, fmt.Errorf("unsupported member: %s", member)
+	}
+
+	var indices []int
+	for len(selector) != 0 {
+		end := strings.IndexByte(selector, ')')
+		if selector[0] != '(' || end == -1 {
+			return 0, fmt.Errorf("invalid selector: %s", selector)
+		}
+
+		i, err := strconv.Atoi(selector[1:end])
+		if err != nil {
+			return 0, err
+		}
+		indices = append(indices, i)
+		selector = selector[end+1:]
+	}
+
+	switch len(indices) {
+	case 1:
+		return indices[0], nil
+	case 2:
+		return indices[0]*4 + indices[1], nil
+	default:
+		return 0, fmt.Errorf("invalid selector: %s", selector)
+	}
+}
+
+// parseTarget resolves a channel target (node id/transform sid followed by an optional selector)
+func (im *importer) parseTarget(target string) (*sceneNode, int, int, error) {
+	id, path, found := strings.Cut(target, "/")
+	if !found {
+		return nil, 0, 0, fmt.Errorf("unsupported animation target: %s", target)
+	}
+
+	node, ok := im.nodesByID[id]
+	if !ok {
+		// nodes outside the imported visual scene
+		return nil, 0, 0, nil
+	}
+
+	sid := path
+	if i := strings.IndexAny(path, ".("); i != -1 {
+		sid = path[:i]
+	}
+
+	for t := range node.transforms {
+		if node.transforms[t].sid != sid {
+			continue
+		}
+
+		if selector := path[len(sid):]; len(selector) != 0 {
+			index, err := memberIndex(node.transforms[t].kind, selector)
+			if err != nil {
+				return nil, 0, 0, fmt.Errorf("animation target %s: %w", target, err)
+			}
+			if index < 0 || index >= len(node.transforms[t].values) {
+				return nil, 0, 0, fmt.Errorf("animation target %s is out of range", target)
+			}
+			return node, t, index, nil
+		}
+
+		return node, t, -1, nil
+	}
+
+	return nil, 0, 0, fmt.Errorf("animation target %s: unknown transform: %s", target, sid)
+}
+
+func (im *importer) importChannel(anim *Animation, c *Channel, samplers map[string]*Sampler) (*channel, error) {
+	node, transform, index, err := im.parseTarget(c.Target)
+	if err != nil || node == nil {
+		return nil, err
+	}
+
+	sampler, ok := samplers[fragment(c.Source)]
+	if !ok {
+		return nil, fmt.Errorf("animation channel has an unknown sampler: %s", c.Source)
+	}
+
+	out := &channel{node: node, transform: transform, index: index}
+
+	for _, in := range sampler.Inputs {
+		src := findSource(anim.Sources, in.Source)
+		if src == nil {
+			return nil, fmt.Errorf("animation sampler %s: unknown source: %s", sampler.ID, in.Source)
+		}
+
+		switch in.Semantic {
+		case "INPUT":
+			if out.times, _, err = sourceValues(src); err != nil {
+				return nil, err
+			}
+		case "OUTPUT":
+			if out.values, _, err = sourceValues(src); err != nil {
+				return nil, err
+			}
+		case "INTERPOLATION":
+			names, err := sourceNames(src)
+			if err != nil {
+				return nil, err
+			}
+			out.step = len(names) != 0 && names[0] == "STEP"
+		}
+	}
+
+	if len(out.times) == 0 {
+		return nil, nil
+	}
+
+	out.components = 1
+	if index == -1 {
+		out.components = len(node.transforms[transform].values)
+	}
+
+	if len(out.values) != len(out.times)*out.components {
+		return nil, fmt.Errorf("animation sampler %s output does not match its input", sampler.ID)
+	}
+
+	return out, nil
+}
+
+// importAnimations samples every channel at the file frame rate into the nodes key frames
+func (im *importer) importAnimations() error {
+	if im.doc.LibraryAnimations == nil {
+		return nil
+	}
+
+	var (
+		channels []*channel
+		duration float64
+		visit    func(anim *Animation) error
+	)
+
+	visit = func(anim *Animation) error {
+		samplers := make(map[string]*Sampler)
+		for i := range anim.Samplers {
+			samplers[anim.Samplers[i].ID] = &anim.Samplers[i]
+		}
+
+		for i := range anim.Channels {
+			c, err := im.importChannel(anim, &anim.Channels[i], samplers)
+			if err != nil {
+				return err
+			}
+			if c != nil {
+				channels = append(channels, c)
+				duration = max(duration, c.times[len(c.times)-1])
+			}
+		}
+
+		for i := range anim.Animations {
+			if err := visit(&anim.Animations[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := range im.doc.LibraryAnimations.Animations {
+		if err := visit(&im.doc.LibraryAnimations.Animations[i]); err != nil {
+			return err
+		}
+	}
+
+	if len(channels) == 0 {
+		return nil
+	}
+
+	rate := float64(im.file.FrameRate)
+	lastFrame := int(math.Round(duration * rate))
+	if lastFrame > math.MaxUint16 {
+		return fmt.Errorf("animation is too long: %d frames", lastFrame)
+	}
+
+	im.file.FirstFrame = 0
+	im.file.LastFrame = uint16(lastFrame)
+
+	nodeChannels := make(map[*sceneNode][]*channel)
+	var animated []*sceneNode
+	for _, c := range channels {
+		if _, ok := nodeChannels[c.node]; !ok {
+			animated = append(animated, c.node)
+		}
+		nodeChannels[c.node] = append(nodeChannels[c.node], c)
+	}
+
+	for _, node := range animated {
+		transforms := make([]transform, len(node.transforms))
+
+		frames := make([]scw.KeyFrame, lastFrame+1)
+		for f := range frames {
+			for t := range transforms {
+				transforms[t] = node.transforms[t]
+				transforms[t].values = slices.Clone(node.transforms[t].values)
+			}
+
+			time := float64(f) / rate
+			for _, c := range nodeChannels[node] {
+				values := transforms[c.transform].values
+				if c.index != -1 {
+					values = values[c.index : c.index+1]
+				}
+				c.sample(time, values)
+			}
+
+			frame, err := keyFrame(transforms, uint16(f))
+			if err != nil {
+				return fmt.Errorf("node %s: %w", node.name, err)
+			}
+			frames[f] = frame
+		}
+
+		im.file.Nodes[node.index].Frames = frames
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package dae
+
+import (
+	"strings"
+	"testing"
+)
+
+// skinnedTriangle is a triangle skinned to one joint, the placeholders are the input offsets and set
+const skinnedTriangle = `<?xml version="1.0" encoding="UTF-8"?>
+<COLLADA xmlns="http://www.collada.org/2005/11/COLLADASchema" version="1.4.1">
+  <library_geometries>
+    <geometry id="tri-mesh" name="tri">
+      <mesh>
+        <source id="tri-position">
+          <float_array id="tri-position-array" count="9">0 0 0 1 0 0 0 1 0</float_array>
+          <technique_common>
+            <accessor source="#tri-position-array" count="3" stride="3">
+              <param name="X" type="float"/><param name="Y" type="float"/><param name="Z" type="float"/>
+            </accessor>
+          </technique_common>
+        </source>
+        <vertices id="tri-vertices">
+          <input semantic="POSITION" source="#tri-position"/>
+        </vertices>
+        <triangles count="1">
+          <input semantic="VERTEX" source="#tri-vertices" offset="{offset}" set="{set}"/>
+          <p>0 1 2</p>
+        </triangles>
+      </mesh>
+    </geometry>
+  </library_geometries>
+  <library_controllers>
+    <controller id="tri-skin" name="tri">
+      <skin source="#tri-mesh">
+        <source id="tri-skin-joints">
+          <Name_array id="tri-skin-joints-array" count="1">root</Name_array>
+          <technique_common>
+            <accessor source="#tri-skin-joints-array" count="1" stride="1">
+              <param name="JOINT" type="name"/>
+            </accessor>
+          </technique_common>
+        </source>
+        <source id="tri-skin-bind-poses">
+          <float_array id="tri-skin-bind-poses-array" count="16">1 0 0 0 0 1 0 0 0 0 1 0 0 0 0 1</float_array>
+          <technique_common>
+            <accessor source="#tri-skin-bind-poses-array" count="1" stride="16">
+              <param name="TRANSFORM" type="float4x4"/>
+            </accessor>
+          </technique_common>
+        </source>
+        <source id="tri-skin-weights">
+          <float_array id="tri-skin-weights-array" count="1">1</float_array>
+          <technique_common>
+            <accessor source="#tri-skin-weights-array" count="1" stride="1">
+              <param name="WEIGHT" type="float"/>
+            </accessor>
+          </technique_common>
+        </source>
+        <joints>
+          <input semantic="JOINT" source="#tri-skin-joints"/>
+          <input semantic="INV_BIND_MATRIX" source="#tri-skin-bind-poses"/>
+        </joints>
+        <vertex_weights count="3">
+          <input semantic="JOINT" source="#tri-skin-joints" offset="{joint}"/>
+          <input semantic="WEIGHT" source="#tri-skin-weights" offset="{weight}"/>
+          <vcount>1 1 1</vcount>
+          <v>0 0 0 0 0 0</v>
+        </vertex_weights>
+      </skin>
+    </controller>
+  </library_controllers>
+  <library_visual_scenes>
+    <visual_scene id="scene">
+      <node id="root" sid="root" name="root" type="JOINT"/>
+      <node id="mesh" name="mesh">
+        <instance_controller url="#tri-skin">
+          <skeleton>#root</skeleton>
+        </instance_controller>
+      </node>
+    </visual_scene>
+  </library_visual_scenes>
+  <scene>
+    <instance_visual_scene url="#scene"/>
+  </scene>
+</COLLADA>
+`
+
+func importSkinnedTriangle(offset, set, joint, weight string) error {
+	data := strings.NewReplacer("{offset}", offset, "{set}", set, "{joint}", joint, "{weight}", weight).Replace(skinnedTriangle)
+	_, err := Import([]byte(data), "")
+	return err
+}
+
+func TestImportSkinnedTriangle(t *testing.T) {
+	if err := importSkinnedTriangle("0", "0", "0", "1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportNegativeOffsets(t *testing.T) {
+	for _, test := range []struct {
+		name                       string
+		offset, set, joint, weight string
+	}{
+		{"input offset", "-2", "0", "0", "1"},
+		{"input set", "0", "-1", "0", "1"},
+		{"joint offset", "0", "0", "-2", "1"},
+		{"weight offset", "0", "0", "0", "-2"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := importSkinnedTriangle(test.offset, test.set, test.joint, test.weight); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package gltf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrSparseAccessor sparse accessors are not supported
+	ErrSparseAccessor = errors.New("sparse accessors are not supported")
+)
+
+// loadBuffers resolves every buffer of the document, bin is the GLB BIN chunk and dir is used for external uris
+func loadBuffers(doc *Document, bin []byte, dir string) ([][]byte, error) {
+	buffers := make([][]byte, len(doc.Buffers))
+
+	for i, buf := range doc.Buffers {
+		var (
+			data []byte
+			err  error
+		)
+
+		switch {
+		case len(buf.URI) == 0:
+			if i != 0 || bin == nil {
+				return nil, fmt.Errorf("buffer %d has no uri", i)
+			}
+			data = bin
+		case strings.HasPrefix(buf.URI, "data:"):
+			comma := strings.IndexByte(buf.URI, ',')
+			if comma == -1 || !strings.HasSuffix(buf.URI[:comma], ";base64") {
+				return nil, fmt.Errorf("buffer %d: unsupported data uri", i)
+			}
+			if data, err = base64.StdEncoding.DecodeString(buf.URI[comma+1:]); err != nil {
+				return nil, err
+			}
+		default:
+			var path string
+			if path, err = url.PathUnescape(buf.URI); err != nil {
+				return nil, err
+			}
+			if data, err = os.ReadFile(filepath.Join(dir, path)); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(data) < buf.ByteLength {
+			return nil, fmt.Errorf("buffer %d is shorter than its declared length", i)
+		}
+
+		buffers[i] = data
+	}
+
+	return buffers, nil
+}
+
+func componentSize(componentType int) (int, error) {
+	switch componentType {
+	case ComponentByte, ComponentUnsignedByte:
+		return 1, nil
+	case ComponentShort, ComponentUnsignedShort:
+		return 2, nil
+	case ComponentUnsignedInt, ComponentFloat:
+		return 4, nil
+	default:
+		return 0, fmt.Errorf("unsupported accessor component type: %d", componentType)
+	}
+}
+
+func readComponent(data []byte, componentType int, normalized bool) float64 {
+	switch componentType {
+	case ComponentByte:
+		v := float64(int8(data[0]))
+		if normalized {
+			return max(v/127, -1)
+		}
+		return v
+	case ComponentUnsignedByte:
+		v := float64(data[0])
+		if normalized {
+			return v / 255
+		}
+		return v
+	case ComponentShort:
+		v := float64(int16(binary.LittleEndian.Uint16(data)))
+		if normalized {
+			return max(v/32767, -1)
+		}
+		return v
+	case ComponentUnsignedShort:
+		v := float64(binary.LittleEndian.Uint16(data))
+		if normalized {
+			return v / 65535
+		}
+		return v
+	case ComponentUnsignedInt:
+		return float64(binary.LittleEndian.Uint32(data))
+	default:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(data)))
+	}
+}
+
+// readAccessor returns the flattened accessor values and the number of components per element
+//
+// values are returned as float64 which is exact for every component type, including uint32 indices
+func readAccessor(doc *Document, buffers [][]byte, index int) ([]float64, int, error) {
+	if index < 0 || index >= len(doc.Accessors) {
+		return nil, 0, fmt.Errorf("invalid accessor index: %d", index)
+	}
+
+	accessor := &doc.Accessors[index]
+	if len(accessor.Sparse) != 0 {
+		return nil, 0, ErrSparseAccessor
+	}
+
+	components := componentsCount(accessor.Type)
+
+	// the count is bounded so the values can be allocated, views and accessors must be in their buffer
+	if accessor.Count < 0 || accessor.Count > math.MaxInt32/components {
+		return nil, 0, fmt.Errorf("accessor %d: invalid count %d", index, accessor.Count)
+	}
+
+	if accessor.ByteOffset < 0 {
+		return nil, 0, fmt.Errorf("accessor %d: invalid byte offset %d", index, accessor.ByteOffset)
+	}
+
+	size, err := componentSize(accessor.ComponentType)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// accessors without a buffer view are initialized with zeros, they are bounded
+	// as if their values were stored in the buffers of the document
+	if accessor.BufferView == nil {
+		length := 0
+		for _, buffer := range buffers {
+			length += len(buffer)
+		}
+
+		if accessor.Count > length/(size*components) {
+			return nil, 0, fmt.Errorf("accessor %d: count %d without a buffer view is larger than the buffers", index, accessor.Count)
+		}
+		return make([]float64, accessor.Count*components), components, nil
+	}
+
+	if *accessor.BufferView < 0 || *accessor.BufferView >= len(doc.BufferViews) {
+		return nil, 0, fmt.Errorf("accessor %d: invalid buffer view %d", index, *accessor.BufferView)
+	}
+
+	view := &doc.BufferViews[*accessor.BufferView]
+	if view.Buffer < 0 || view.Buffer >= len(buffers) {
+		return nil, 0, fmt.Errorf("buffer view %d: invalid buffer %d", *accessor.BufferView, view.Buffer)
+	}
+
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteStride < 0 {
+		return nil, 0, fmt.Errorf("buffer view %d: negative byte offset, length or stride", *accessor.BufferView)
+	}
+
+	buffer := buffers[view.Buffer]
+	if view.ByteOffset > len(buffer) || view.ByteLength > len(buffer)-view.ByteOffset {
+		return nil, 0, fmt.Errorf("buffer view %d is out of its buffer range", *accessor.BufferView)
+	}
+	data := buffer[view.ByteOffset : view.ByteOffset+view.ByteLength]
+
+	elementSize := size * components
+	stride := view.ByteStride
+	if stride == 0 {
+		stride = elementSize
+	}
+
+	// written as divisions so huge counts, strides and offsets can not overflow
+	if accessor.Count > 0 {
+		available := len(data) - accessor.ByteOffset - elementSize
+		if accessor.ByteOffset > len(data) || available < 0 || accessor.Count-1 > available/stride {
+			return nil, 0, fmt.Errorf("accessor %d is out of its buffer view range", index)
+		}
+	}
+
+	values := make([]float64, accessor.Count*components)
+
+	for i := range accessor.Count {
+		start := accessor.ByteOffset + i*stride
+		for c := range components {
+			values[i*components+c] = readComponent(data[start+c*size:], accessor.ComponentType, accessor.Normalized)
+		}
+	}
+
+	return values, components, nil
+}
@@ -0,0 +1,100 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// accessorDocument is a document with one buffer of 4 floats (1, 2, 3, 4) and one view on it
+func accessorDocument() (*Document, [][]byte) {
+	data := make([]byte, 16)
+	for i := range 4 {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(float32(i+1)))
+	}
+
+	doc := &Document{
+		Buffers:     []Buffer{{ByteLength: len(data)}},
+		BufferViews: []BufferView{{Buffer: 0, ByteLength: len(data)}},
+		Accessors: []Accessor{{
+			BufferView:    intPtr(0),
+			ComponentType: ComponentFloat,
+			Count:         4,
+			Type:          TypeScalar,
+		}},
+	}
+	return doc, [][]byte{data}
+}
+
+func TestReadAccessor(t *testing.T) {
+	doc, buffers := accessorDocument()
+	doc.Accessors[0].ByteOffset = 4
+	doc.Accessors[0].Count = 3
+
+	values, components, err := readAccessor(doc, buffers, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if components != 1 || len(values) != 3 || values[0] != 2 || values[2] != 4 {
+		t.Fatalf("got %v with %d components, expected [2 3 4]", values, components)
+	}
+}
+
+func TestReadAccessorWithoutView(t *testing.T) {
+	doc, buffers := accessorDocument()
+	doc.Accessors[0].BufferView = nil
+
+	// zeros, as many as the 16 bytes of the buffer can hold
+	values, _, err := readAccessor(doc, buffers, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 4 || values[0] != 0 || values[3] != 0 {
+		t.Fatalf("got %v, expected 4 zeros", values)
+	}
+}
+
+func TestReadAccessorInvalid(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		modify func(doc *Document)
+	}{
+		{"negative count", func(doc *Document) { doc.Accessors[0].Count = -1 }},
+		{"negative count without view", func(doc *Document) {
+			doc.Accessors[0].BufferView = nil
+			doc.Accessors[0].Count = -1
+		}},
+		{"huge count", func(doc *Document) { doc.Accessors[0].Count = math.MaxInt }},
+		{"count without view larger than the buffers", func(doc *Document) {
+			doc.Accessors[0].BufferView = nil
+			doc.Accessors[0].Type = TypeVec4
+			doc.Accessors[0].Count = math.MaxInt32 / 4
+		}},
+		{"count out of the view", func(doc *Document) { doc.Accessors[0].Count = 5 }},
+		{"negative accessor offset", func(doc *Document) { doc.Accessors[0].ByteOffset = -4 }},
+		{"accessor offset out of the view", func(doc *Document) { doc.Accessors[0].ByteOffset = math.MaxInt }},
+		{"negative view offset", func(doc *Document) { doc.BufferViews[0].ByteOffset = -4 }},
+		{"negative view length", func(doc *Document) { doc.BufferViews[0].ByteLength = -4 }},
+		{"negative view stride", func(doc *Document) { doc.BufferViews[0].ByteStride = -4 }},
+		{"huge view stride", func(doc *Document) { doc.BufferViews[0].ByteStride = math.MaxInt }},
+		{"view out of the buffer", func(doc *Document) {
+			doc.BufferViews[0].ByteOffset = 8
+			doc.BufferViews[0].ByteLength = math.MaxInt
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			doc, buffers := accessorDocument()
+			test.modify(doc)
+
+			if _, _, err := readAccessor(doc, buffers, 0); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
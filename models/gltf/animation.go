@@ -0,0 +1,113 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+// frameRate returns the file frame rate, falling back to the importer default for files without one
+func frameRate(file *scw.File) float32 {
+	if file.FrameRate == 0 {
+		return scw.DefaultFrameRate
+	}
+	return float32(file.FrameRate)
+}
+
+// animatedFrames returns the node frames inside the [FirstFrame, LastFrame] range of the header
+//
+// files with both set to 0 have no range, so every frame is kept
+func animatedFrames(file *scw.File, node *scw.Node) []scw.KeyFrame {
+	if file.FirstFrame == 0 && file.LastFrame == 0 {
+		return node.Frames
+	}
+
+	var frames []scw.KeyFrame
+	for _, frame := range node.Frames {
+		if frame.ID >= file.FirstFrame && frame.ID <= file.LastFrame {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+func normalizedRotation(q scw.Quaternion) [4]float32 {
+	length := float32(math.Sqrt(float64(q.X*q.X + q.Y*q.Y + q.Z*q.Z + q.W*q.W)))
+	if length == 0 {
+		return [4]float32{0, 0, 0, 1}
+	}
+	return [4]float32{q.X / length, q.Y / length, q.Z / length, q.W / length}
+}
+
+// exportAnimation converts the key frames of every animated node to a single glTF animation
+func (e *exporter) exportAnimation() {
+	var (
+		animation Animation
+		// nodes sampled at the same frames share their input accessor
+		inputs = make(map[string]int)
+		rate   = frameRate(e.file)
+	)
+
+	for i := range e.file.Nodes {
+		frames := animatedFrames(e.file, &e.file.Nodes[i])
+		if len(frames) < 2 {
+			continue
+		}
+
+		key := make([]byte, 2*len(frames))
+		for f, frame := range frames {
+			binary.LittleEndian.PutUint16(key[f*2:], frame.ID)
+		}
+
+		input, ok := inputs[string(key)]
+		if !ok {
+			times := make([]float32, len(frames))
+			for f, frame := range frames {
+				times[f] = float32(int(frame.ID)-int(e.file.FirstFrame)) / rate
+			}
+			// input accessors must define min and max
+			input = e.buffer.addFloats(times, TypeScalar, 0, true)
+			inputs[string(key)] = input
+		}
+
+		translations := make([]float32, 0, 3*len(frames))
+		rotations := make([]float32, 0, 4*len(frames))
+		scales := make([]float32, 0, 3*len(frames))
+
+		for _, frame := range frames {
+			rotation := normalizedRotation(frame.Rotation)
+			translations = append(translations, frame.Translation.X, frame.Translation.Y, frame.Translation.Z)
+			rotations = append(rotations, rotation[:]...)
+			scales = append(scales, frame.Scale.X, frame.Scale.Y, frame.Scale.Z)
+		}
+
+		node := i
+		for _, channel := range []struct {
+			path   string
+			typ    string
+			values []float32
+		}{
+			{"translation", TypeVec3, translations},
+			{"rotation", TypeVec4, rotations},
+			{"scale", TypeVec3, scales},
+		} {
+			animation.Samplers = append(animation.Samplers, AnimationSampler{
+				Input:         input,
+				Interpolation: "LINEAR",
+				Output:        e.buffer.addFloats(channel.values, channel.typ, 0, false),
+			})
+			animation.Channels = append(animation.Channels, AnimationChannel{
+				Sampler: len(animation.Samplers) - 1,
+				Target: ChannelTarget{
+					Node: ptr(node),
+					Path: channel.path,
+				},
+			})
+		}
+	}
+
+	if len(animation.Channels) > 0 {
+		e.doc.Animations = append(e.doc.Animations, animation)
+	}
+}
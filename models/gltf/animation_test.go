@@ -0,0 +1,29 @@
+package gltf
+
+import (
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models/internal/modeltest"
+)
+
+func TestAnimationRoundTrip(t *testing.T) {
+	file := modeltest.LoadFixture(t, "animated.scw")
+	imported := roundTrip(t, file, ".gltf")
+
+	if imported.FirstFrame != file.FirstFrame || imported.LastFrame != file.LastFrame {
+		t.Fatalf("frames %d-%d, expected %d-%d", imported.FirstFrame, imported.LastFrame, file.FirstFrame, file.LastFrame)
+	}
+
+	// every frame of the root is a key frame
+	frames, out := file.Nodes[0].Frames, imported.Nodes[0].Frames
+	if len(out) != len(frames) {
+		t.Fatalf("%d frames, expected %d", len(out), len(frames))
+	}
+
+	for i, frame := range frames {
+		if out[i].ID != frame.ID || !modeltest.SameRotation(out[i].Rotation, frame.Rotation) ||
+			out[i].Translation != frame.Translation || out[i].Scale != frame.Scale {
+			t.Fatalf("frame %+v imported as %+v", frame, out[i])
+		}
+	}
+}
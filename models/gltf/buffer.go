@@ -0,0 +1,130 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// bufferBuilder packs accessors data into a single binary buffer (buffer 0)
+type bufferBuilder struct {
+	doc  *Document
+	data []byte
+}
+
+// align pads the buffer so the next view starts on a 4 bytes boundary (required by the spec)
+func (b *bufferBuilder) align() {
+	for len(b.data)%4 != 0 {
+		b.data = append(b.data, 0)
+	}
+}
+
+func (b *bufferBuilder) addView(data []byte, target int) int {
+	b.align()
+	b.doc.BufferViews = append(b.doc.BufferViews, BufferView{
+		Buffer:     0,
+		ByteOffset: len(b.data),
+		ByteLength: len(data),
+		Target:     target,
+	})
+	b.data = append(b.data, data...)
+	return len(b.doc.BufferViews) - 1
+}
+
+func (b *bufferBuilder) addAccessor(accessor Accessor) int {
+	b.doc.Accessors = append(b.doc.Accessors, accessor)
+	return len(b.doc.Accessors) - 1
+}
+
+func componentsCount(typ string) int {
+	switch typ {
+	case TypeVec2:
+		return 2
+	case TypeVec3:
+		return 3
+	case TypeVec4:
+		return 4
+	case TypeMat4:
+		return 16
+	default:
+		return 1
+	}
+}
+
+// addFloats adds a float accessor, min/max are only required for POSITION but they are cheap to compute
+func (b *bufferBuilder) addFloats(values []float32, typ string, target int, bounds bool) int {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+
+	components := componentsCount(typ)
+
+	accessor := Accessor{
+		BufferView:    ptr(b.addView(data, target)),
+		ComponentType: ComponentFloat,
+		Count:         len(values) / components,
+		Type:          typ,
+	}
+
+	if bounds && accessor.Count > 0 {
+		accessor.Min = make([]float32, components)
+		accessor.Max = make([]float32, components)
+		copy(accessor.Min, values[:components])
+		copy(accessor.Max, values[:components])
+		for i, v := range values {
+			c := i % components
+			accessor.Min[c] = min(accessor.Min[c], v)
+			accessor.Max[c] = max(accessor.Max[c], v)
+		}
+	}
+
+	return b.addAccessor(accessor)
+}
+
+func (b *bufferBuilder) addIndices(indices []uint32) int {
+	maxIndex := uint32(0)
+	for _, idx := range indices {
+		maxIndex = max(maxIndex, idx)
+	}
+
+	var (
+		data          []byte
+		componentType int
+	)
+
+	if maxIndex <= math.MaxUint16 {
+		componentType = ComponentUnsignedShort
+		data = make([]byte, 2*len(indices))
+		for i, idx := range indices {
+			binary.LittleEndian.PutUint16(data[i*2:], uint16(idx))
+		}
+	} else {
+		componentType = ComponentUnsignedInt
+		data = make([]byte, 4*len(indices))
+		for i, idx := range indices {
+			binary.LittleEndian.PutUint32(data[i*4:], idx)
+		}
+	}
+
+	return b.addAccessor(Accessor{
+		BufferView:    ptr(b.addView(data, TargetElementArrayBuffer)),
+		ComponentType: componentType,
+		Count:         len(indices),
+		Type:          TypeScalar,
+	})
+}
+
+func (b *bufferBuilder) addJoints(joints []byte) int {
+	return b.addAccessor(Accessor{
+		BufferView:    ptr(b.addView(joints, TargetArrayBuffer)),
+		ComponentType: ComponentUnsignedByte,
+		Count:         len(joints) / 4,
+		Type:          TypeVec4,
+	})
+}
+
+// bytes returns the final buffer, padded to 4 bytes
+func (b *bufferBuilder) bytes() []byte {
+	b.align()
+	return b.data
+}
@@ -0,0 +1,567 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/PeterHackz/conv3d/models/internal/atomicfile"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+type exporter struct {
+	file   *scw.File
+	doc    *Document
+	buffer bufferBuilder
+
+	nodes      map[string]int
+	materials  map[string]int
+	cameras    map[string]int
+	textures   map[string]int
+	geometries map[string]*geometryData
+	skins      map[string]int
+}
+
+// geometryData holds the accessors of an already exported geometry so instances can share them
+type geometryData struct {
+	attributes map[string]int
+	primitives []primitiveData
+}
+
+type primitiveData struct {
+	symbol  string // IndexArray.Name, bound to a material by the node instance
+	indices int
+}
+
+// Export converts a loaded scw File to a glTF document and its binary buffer
+//
+// the document references the buffer as buffer 0 without an uri, callers decide where it is stored
+func Export(file *scw.File) (*Document, []byte, error) {
+	doc := &Document{
+		Asset: Asset{
+			Version:   "2.0",
+			Generator: "conv3d",
+		},
+	}
+
+	e := &exporter{
+		file:       file,
+		doc:        doc,
+		buffer:     bufferBuilder{doc: doc},
+		nodes:      make(map[string]int),
+		materials:  make(map[string]int),
+		cameras:    make(map[string]int),
+		textures:   make(map[string]int),
+		geometries: make(map[string]*geometryData),
+		skins:      make(map[string]int),
+	}
+
+	for _, mat := range file.Materials {
+		e.exportMaterial(mat)
+	}
+
+	for _, cam := range file.Cameras {
+		e.exportCamera(cam)
+	}
+
+	if err := e.exportNodes(); err != nil {
+		return nil, nil, err
+	}
+
+	e.exportAnimation()
+
+	bin := e.buffer.bytes()
+	if len(bin) > 0 {
+		doc.Buffers = []Buffer{{ByteLength: len(bin)}}
+	}
+
+	return doc, bin, nil
+}
+
+// WriteFile exports file as a .gltf document next to an external .bin buffer with the same base name,
+// each of them replaces its file only once it was fully written
+func WriteFile(filename string, file *scw.File) error {
+	doc, bin, err := Export(file)
+	if err != nil {
+		return err
+	}
+
+	binName := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".bin"
+
+	if len(doc.Buffers) > 0 {
+		doc.Buffers[0].URI = binName
+		if err = atomicfile.WriteFile(filepath.Join(filepath.Dir(filename), binName), bin); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(filename, data)
+}
+
+func (e *exporter) exportTexture(path string) int {
+	if idx, ok := e.textures[path]; ok {
+		return idx
+	}
+
+	e.doc.Images = append(e.doc.Images, Image{URI: path})
+	e.doc.Textures = append(e.doc.Textures, Texture{Source: ptr(len(e.doc.Images) - 1)})
+
+	idx := len(e.doc.Textures) - 1
+	e.textures[path] = idx
+	return idx
+}
+
+// exportMaterial maps the scw (phong like) material to a non-metallic PBR material
+func (e *exporter) exportMaterial(mat *scw.Material) {
+	vars := &mat.Variables
+
+	pbr := &PBRMetallicRoughness{
+		MetallicFactor:  ptr(float32(0)),
+		RoughnessFactor: ptr(float32(1)),
+	}
+
+	material := Material{
+		Name:                 mat.Name,
+		PBRMetallicRoughness: pbr,
+	}
+
+	if vars.Diffuse.UseText2D {
+		pbr.BaseColorTexture = &TextureInfo{Index: e.exportTexture(vars.Diffuse.Texture2D)}
+	} else {
+		pbr.BaseColorFactor = ptr(vars.Diffuse.Color.Floats())
+	}
+
+	if vars.Opacity > 0 && vars.Opacity < 1 {
+		if pbr.BaseColorFactor == nil {
+			pbr.BaseColorFactor = &[4]float32{1, 1, 1, 1}
+		}
+		pbr.BaseColorFactor[3] *= vars.Opacity
+	}
+
+	if vars.Opacity > 0 && vars.Opacity < 1 || len(vars.OpacityTex2D) != 0 {
+		material.AlphaMode = "BLEND"
+	}
+
+	if len(vars.NormalTex2D) != 0 {
+		material.NormalTexture = &TextureInfo{Index: e.exportTexture(vars.NormalTex2D)}
+	}
+
+	if vars.Emission.UseText2D {
+		material.EmissiveTexture = &TextureInfo{Index: e.exportTexture(vars.Emission.Texture2D)}
+		material.EmissiveFactor = &[3]float32{1, 1, 1}
+	} else if color := vars.Emission.Color.Floats(); color[0] != 0 || color[1] != 0 || color[2] != 0 {
+		material.EmissiveFactor = &[3]float32{color[0], color[1], color[2]}
+	}
+
+	e.doc.Materials = append(e.doc.Materials, material)
+	e.materials[mat.Name] = len(e.doc.Materials) - 1
+}
+
+func (e *exporter) exportCamera(cam *scw.Camera3D) {
+	// scw cameras mirror COLLADA ones which use degrees
+	yfov := cam.Yfov
+	if yfov == 0 && cam.AspectRatio != 0 {
+		yfov = float32(2 * math.Atan(math.Tan(float64(cam.Xfov)*math.Pi/360)/float64(cam.AspectRatio)) * 180 / math.Pi)
+	}
+
+	perspective := &Perspective{
+		AspectRatio: cam.AspectRatio,
+		Yfov:        yfov * math.Pi / 180,
+		Znear:       cam.ZNear,
+	}
+
+	if cam.ZFar > 0 {
+		perspective.Zfar = ptr(cam.ZFar)
+	}
+
+	e.doc.Cameras = append(e.doc.Cameras, Camera{
+		Name:        cam.Name,
+		Type:        "perspective",
+		Perspective: perspective,
+	})
+	e.cameras[cam.Name] = len(e.doc.Cameras) - 1
+}
+
+func nodeTransform(node *scw.Node, out *Node) {
+	if len(node.Frames) == 0 {
+		return
+	}
+
+	// the first frame is the rest pose of the node
+	frame := node.Frames[0]
+
+	out.Rotation = ptr(normalizedRotation(frame.Rotation))
+	out.Translation = &[3]float32{frame.Translation.X, frame.Translation.Y, frame.Translation.Z}
+	out.Scale = &[3]float32{frame.Scale.X, frame.Scale.Y, frame.Scale.Z}
+}
+
+func (e *exporter) exportNodes() error {
+	nodes := e.file.Nodes
+
+	for i := range nodes {
+		var out Node
+		out.Name = nodes[i].Name
+		nodeTransform(&nodes[i], &out)
+		e.doc.Nodes = append(e.doc.Nodes, out)
+		e.nodes[nodes[i].Name] = i
+	}
+
+	var roots []int
+
+	for i := range nodes {
+		if parent, ok := e.nodes[nodes[i].ParentName]; ok && len(nodes[i].ParentName) != 0 {
+			e.doc.Nodes[parent].Children = append(e.doc.Nodes[parent].Children, i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	// joints must all exist before skins are exported, so instances are handled last
+	for i := range nodes {
+		instances := nodes[i].Instances
+
+		if len(instances) == 1 {
+			if err := e.attachInstance(i, &instances[0]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// a glTF node can only hold one mesh/camera, extra instances become children
+		for j := range instances {
+			e.doc.Nodes = append(e.doc.Nodes, Node{Name: nodes[i].Name + "/" + instances[j].Target})
+			child := len(e.doc.Nodes) - 1
+			e.doc.Nodes[i].Children = append(e.doc.Nodes[i].Children, child)
+			if err := e.attachInstance(child, &instances[j]); err != nil {
+				return err
+			}
+		}
+	}
+
+	e.doc.Scenes = []Scene{{Nodes: roots}}
+	e.doc.Scene = ptr(0)
+
+	return nil
+}
+
+func (e *exporter) findGeometry(name string) *scw.Geometry {
+	for _, geom := range e.file.Geometries {
+		if geom.Name == name {
+			return geom
+		}
+	}
+	return nil
+}
+
+func (e *exporter) attachInstance(nodeIdx int, instance *scw.NodeInstance) error {
+	switch instance.Type {
+	case "GEOM", "CONT":
+		geom := e.findGeometry(instance.Target)
+		if geom == nil {
+			return fmt.Errorf("node instance references unknown geometry: %s", instance.Target)
+		}
+
+		mesh, err := e.exportMesh(geom, instance.Materials)
+		if err != nil {
+			return err
+		}
+		e.doc.Nodes[nodeIdx].Mesh = ptr(mesh)
+
+		if instance.Type == "CONT" && len(geom.Skins.Joints) > 0 {
+			skin, err := e.exportSkin(geom)
+			if err != nil {
+				return err
+			}
+			e.doc.Nodes[nodeIdx].Skin = ptr(skin)
+		}
+	case "CAME":
+		cam, ok := e.cameras[instance.Target]
+		if !ok {
+			return fmt.Errorf("node instance references unknown camera: %s", instance.Target)
+		}
+		e.doc.Nodes[nodeIdx].Camera = ptr(cam)
+	default:
+		return fmt.Errorf("unsupported node instance type: %s", instance.Type)
+	}
+	return nil
+}
+
+func (e *exporter) exportMesh(geom *scw.Geometry, bindings []scw.InstanceMaterial) (int, error) {
+	data, ok := e.geometries[geom.Name]
+	if !ok {
+		var err error
+		if data, err = e.exportGeometry(geom); err != nil {
+			return 0, err
+		}
+		e.geometries[geom.Name] = data
+	}
+
+	mesh := Mesh{Name: geom.Name}
+
+	for _, prim := range data.primitives {
+		primitive := Primitive{
+			Attributes: data.attributes,
+			Indices:    ptr(prim.indices),
+		}
+
+		target := prim.symbol
+		for _, binding := range bindings {
+			if binding.Name == prim.symbol {
+				target = binding.Target
+				break
+			}
+		}
+
+		if mat, ok := e.materials[target]; ok {
+			primitive.Material = ptr(mat)
+		}
+
+		mesh.Primitives = append(mesh.Primitives, primitive)
+	}
+
+	e.doc.Meshes = append(e.doc.Meshes, mesh)
+	return len(e.doc.Meshes) - 1, nil
+}
+
+// vertexAttribute is a glTF attribute stream built from one scw SourceArray
+type vertexAttribute struct {
+	name   string
+	typ    string
+	source *scw.SourceArray
+	values []float32
+}
+
+func attributeType(stride byte) (string, error) {
+	switch stride {
+	case 1:
+		return TypeScalar, nil
+	case 2:
+		return TypeVec2, nil
+	case 3:
+		return TypeVec3, nil
+	case 4:
+		return TypeVec4, nil
+	default:
+		return "", fmt.Errorf("unsupported source array stride: %d", stride)
+	}
+}
+
+func (e *exporter) exportGeometry(geom *scw.Geometry) (*geometryData, error) {
+	var (
+		attributes []*vertexAttribute
+		position   *vertexAttribute
+		texcoords  int
+		colors     int
+	)
+
+	for i := range geom.Vertices {
+		src := &geom.Vertices[i]
+
+		typ, err := attributeType(src.Stride)
+		if err != nil {
+			return nil, fmt.Errorf("geometry %s: %w", geom.Name, err)
+		}
+
+		attr := &vertexAttribute{typ: typ, source: src}
+
+		switch {
+		case src.IsPosition():
+			attr.name = "POSITION"
+			position = attr
+		case src.Name == "NORMAL":
+			attr.name = "NORMAL"
+		case src.Name == "TEXCOORD":
+			attr.name = fmt.Sprintf("TEXCOORD_%d", texcoords)
+			texcoords++
+		case src.Name == "COLOR":
+			attr.name = fmt.Sprintf("COLOR_%d", colors)
+			colors++
+		default:
+			// application specific attributes must start with an underscore
+			attr.name = "_" + src.Name
+		}
+
+		attributes = append(attributes, attr)
+	}
+
+	if position == nil {
+		return nil, fmt.Errorf("geometry %s has no POSITION source", geom.Name)
+	}
+
+	if position.typ != TypeVec3 {
+		return nil, fmt.Errorf("geometry %s: POSITION must have a stride of 3", geom.Name)
+	}
+
+	var (
+		joints  []byte
+		weights []float32
+		skinned = len(geom.SkinWeights) > 0
+	)
+
+	// scw primitives index every input separately (like COLLADA), glTF needs a single index per vertex
+	// so every unique combination of input indices becomes a vertex
+	vertices := make(map[string]uint32)
+	data := &geometryData{attributes: make(map[string]int)}
+
+	var primitivesIndices [][]uint32
+
+	for _, prim := range geom.Materials {
+		inputs := int(prim.InputsCount)
+		corners := 3 * int(prim.TrianglesCount)
+
+		if len(prim.IndexBuffer) < corners*inputs {
+			return nil, fmt.Errorf("geometry %s: index array %s is too short", geom.Name, prim.Name)
+		}
+
+		indices := make([]uint32, corners)
+
+		for c := range corners {
+			tuple := prim.IndexBuffer[c*inputs : (c+1)*inputs]
+
+			key := make([]byte, 4*inputs)
+			for j, idx := range tuple {
+				binary.LittleEndian.PutUint32(key[j*4:], idx)
+			}
+
+			if idx, ok := vertices[string(key)]; ok {
+				indices[c] = idx
+				continue
+			}
+
+			idx := uint32(len(vertices))
+			vertices[string(key)] = idx
+			indices[c] = idx
+
+			for _, attr := range attributes {
+				offset := int(attr.source.Index)
+				if offset >= inputs {
+					return nil, fmt.Errorf("geometry %s: source %s uses input %d but index array %s only has %d inputs", geom.Name, attr.source.Name, offset, prim.Name, inputs)
+				}
+
+				stride := int(attr.source.Stride)
+				start := int(tuple[offset]) * stride
+				if start+stride > len(attr.source.Data) {
+					return nil, fmt.Errorf("geometry %s: index %d out of range for source %s", geom.Name, tuple[offset], attr.source.Name)
+				}
+
+				for _, v := range attr.source.Data[start : start+stride] {
+					attr.values = append(attr.values, float32(v))
+				}
+			}
+
+			if skinned {
+				// skin weights are stored per position
+				posIdx := int(tuple[position.source.Index])
+				if posIdx >= len(geom.SkinWeights) {
+					return nil, fmt.Errorf("geometry %s: no skin weight for position %d", geom.Name, posIdx)
+				}
+				j, w := skinWeight(&geom.SkinWeights[posIdx])
+				joints = append(joints, j[:]...)
+				weights = append(weights, w[:]...)
+			}
+		}
+
+		primitivesIndices = append(primitivesIndices, indices)
+	}
+
+	if geom.HasBindMatrix {
+		applyBindMatrix(&geom.BindMatrix, position.values)
+	}
+
+	for _, attr := range attributes {
+		data.attributes[attr.name] = e.buffer.addFloats(attr.values, attr.typ, TargetArrayBuffer, attr == position)
+	}
+
+	if skinned {
+		data.attributes["JOINTS_0"] = e.buffer.addJoints(joints)
+		data.attributes["WEIGHTS_0"] = e.buffer.addFloats(weights, TypeVec4, TargetArrayBuffer, false)
+	}
+
+	for i, prim := range geom.Materials {
+		data.primitives = append(data.primitives, primitiveData{
+			symbol:  prim.Name,
+			indices: e.buffer.addIndices(primitivesIndices[i]),
+		})
+	}
+
+	return data, nil
+}
+
+// skinWeight returns the joints and normalized weights of a vertex, glTF requires weights to sum to 1
+func skinWeight(weight *scw.Weight) ([4]byte, [4]float32) {
+	var (
+		out [4]float32
+		sum float32
+	)
+
+	for i, w := range weight.Weights {
+		sum += float32(w)
+		out[i] = float32(w)
+	}
+
+	if sum == 0 {
+		return [4]byte{}, [4]float32{1, 0, 0, 0}
+	}
+
+	for i := range out {
+		out[i] /= sum
+	}
+
+	return weight.Joints, out
+}
+
+// applyBindMatrix bakes the bind shape matrix into the positions since glTF has no equivalent
+func applyBindMatrix(m *scw.Matrix4x4, positions []float32) {
+	for i := 0; i+2 < len(positions); i += 3 {
+		x, y, z := positions[i], positions[i+1], positions[i+2]
+		positions[i] = m[0][0]*x + m[0][1]*y + m[0][2]*z + m[0][3]
+		positions[i+1] = m[1][0]*x + m[1][1]*y + m[1][2]*z + m[1][3]
+		positions[i+2] = m[2][0]*x + m[2][1]*y + m[2][2]*z + m[2][3]
+	}
+}
+
+// columnMajor flattens a decoded (row major) scw matrix into the glTF column major layout
+func columnMajor(m *scw.Matrix4x4) [16]float32 {
+	var out [16]float32
+	for r := range 4 {
+		for c := range 4 {
+			out[c*4+r] = m[r][c]
+		}
+	}
+	return out
+}
+
+func (e *exporter) exportSkin(geom *scw.Geometry) (int, error) {
+	if idx, ok := e.skins[geom.Name]; ok {
+		return idx, nil
+	}
+
+	skin := Skin{Name: geom.Name}
+
+	var matrices []float32
+
+	for i, joint := range geom.Skins.Joints {
+		node, ok := e.nodes[joint]
+		if !ok {
+			return 0, fmt.Errorf("geometry %s: skin references unknown joint: %s", geom.Name, joint)
+		}
+		skin.Joints = append(skin.Joints, node)
+
+		m := columnMajor(&geom.Skins.InverseBindMatrices[i])
+		matrices = append(matrices, m[:]...)
+	}
+
+	skin.InverseBindMatrices = ptr(e.buffer.addFloats(matrices, TypeMat4, 0, false))
+
+	e.doc.Skins = append(e.doc.Skins, skin)
+	e.skins[geom.Name] = len(e.doc.Skins) - 1
+	return len(e.doc.Skins) - 1, nil
+}
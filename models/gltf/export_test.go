@@ -0,0 +1,219 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models/internal/modeltest"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+// roundTrip exports file to a temporary file with ext and imports it back
+func roundTrip(t *testing.T, file *scw.File, ext string) *scw.File {
+	t.Helper()
+
+	write := WriteFile
+	if ext == ".glb" {
+		write = WriteGLBFile
+	}
+
+	filename := filepath.Join(t.TempDir(), "model"+ext)
+	if err := write(filename, file); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return imported
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"v0_minor0.scw", "v1.scw", "v2.scw", "static.scw", "animated.scw"} {
+		for _, ext := range []string{".gltf", ".glb"} {
+			t.Run(name+ext, func(t *testing.T) {
+				file := modeltest.LoadFixture(t, name)
+				imported := roundTrip(t, file, ext)
+
+				// glTF stores float32 values
+				modeltest.CompareGeometries(t, file, imported, 1e-6)
+				modeltest.CompareScene(t, file, imported)
+			})
+		}
+	}
+}
+
+// quadFile is a hand built scw file with a textured quad under a translated root node,
+// the second triangle reuses position 0 with another texture coordinate
+func quadFile() *scw.File {
+	file := &scw.File{Header: scw.Header{Version: 2, FrameRate: 30}}
+
+	mat := &scw.Material{Name: "red"}
+	mat.Variables.Diffuse.Color = scw.RGBA{255, 0, 0, 255}
+	mat.Variables.Opacity = 0.5
+	file.Materials = append(file.Materials, mat)
+
+	file.Geometries = append(file.Geometries, &scw.Geometry{
+		Name: "quad",
+		Vertices: []scw.SourceArray{
+			{Name: "POSITION", Index: 0, Stride: 3, Data: []float64{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, -2}},
+			{Name: "TEXCOORD", Index: 1, Stride: 2, Data: []float64{0, 0, 1, 1}},
+		},
+		Materials: []scw.IndexArray{{
+			Name:           "sym",
+			InputsCount:    2,
+			TrianglesCount: 2,
+			IndexBuffer:    []uint32{0, 0, 1, 1, 2, 0, 0, 1, 2, 0, 3, 1},
+		}},
+	})
+
+	file.Nodes = []scw.Node{
+		{
+			Name:   "root",
+			Frames: []scw.KeyFrame{{Rotation: scw.Quaternion{W: 1}, Translation: scw.Vector3{X: 1, Y: 2, Z: 3}, Scale: scw.Vector3{X: 1, Y: 1, Z: 1}}},
+		},
+		{
+			Name:       "child",
+			ParentName: "root",
+			Instances: []scw.NodeInstance{{
+				Type:      "GEOM",
+				Target:    "quad",
+				Materials: []scw.InstanceMaterial{{Name: "sym", Target: "red"}},
+			}},
+		},
+	}
+
+	return file
+}
+
+// readFloats reads the float32 values of an accessor straight from the exported buffer
+func readFloats(t *testing.T, doc *Document, bin []byte, idx int) []float32 {
+	t.Helper()
+
+	accessor := doc.Accessors[idx]
+	if accessor.ComponentType != ComponentFloat || accessor.BufferView == nil {
+		t.Fatalf("accessor %d is not a float accessor with a buffer view", idx)
+	}
+
+	view := doc.BufferViews[*accessor.BufferView]
+	count := accessor.Count * map[string]int{TypeScalar: 1, TypeVec2: 2, TypeVec3: 3, TypeVec4: 4}[accessor.Type]
+	if 4*count > view.ByteLength {
+		t.Fatalf("accessor %d does not fit its buffer view", idx)
+	}
+
+	values := make([]float32, count)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(bin[view.ByteOffset+accessor.ByteOffset+4*i:]))
+	}
+	return values
+}
+
+func TestExport(t *testing.T) {
+	doc, bin, err := Export(quadFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Asset.Version != "2.0" {
+		t.Errorf("asset version = %q, want 2.0", doc.Asset.Version)
+	}
+
+	if doc.Scene == nil || len(doc.Scenes) != 1 || !slices.Equal(doc.Scenes[0].Nodes, []int{0}) {
+		t.Fatalf("scenes = %+v, want a single scene with node 0 as root", doc.Scenes)
+	}
+
+	if len(doc.Nodes) != 2 || !slices.Equal(doc.Nodes[0].Children, []int{1}) {
+		t.Fatalf("nodes = %+v, want child as the only child of root", doc.Nodes)
+	}
+
+	if root := doc.Nodes[0]; root.Translation == nil || *root.Translation != [3]float32{1, 2, 3} {
+		t.Errorf("root translation = %v, want [1 2 3]", root.Translation)
+	}
+
+	if len(doc.Buffers) != 1 || doc.Buffers[0].ByteLength != len(bin) {
+		t.Fatalf("buffers = %+v, want one buffer of %d bytes", doc.Buffers, len(bin))
+	}
+
+	for i, view := range doc.BufferViews {
+		if view.ByteOffset%4 != 0 || view.ByteOffset+view.ByteLength > len(bin) {
+			t.Errorf("buffer view %d = %+v is not aligned or out of the buffer", i, view)
+		}
+	}
+
+	child := doc.Nodes[1]
+	if child.Mesh == nil || len(doc.Meshes[*child.Mesh].Primitives) != 1 {
+		t.Fatalf("child node = %+v, want a mesh with one primitive", child)
+	}
+	prim := doc.Meshes[*child.Mesh].Primitives[0]
+
+	if prim.Material == nil || doc.Materials[*prim.Material].Name != "red" {
+		t.Errorf("primitive material = %v, want the red material", prim.Material)
+	}
+
+	// (0 0) (1 1) (2 0) (0 1) (3 1) are the unique index tuples
+	position := doc.Accessors[prim.Attributes["POSITION"]]
+	if position.Type != TypeVec3 || position.Count != 5 {
+		t.Fatalf("POSITION accessor = %+v, want 5 VEC3 values", position)
+	}
+
+	if !slices.Equal(position.Min, []float32{0, 0, -2}) || !slices.Equal(position.Max, []float32{1, 1, 0}) {
+		t.Errorf("POSITION bounds = %v %v, want [0 0 -2] [1 1 0]", position.Min, position.Max)
+	}
+
+	positions := readFloats(t, doc, bin, prim.Attributes["POSITION"])
+	texcoords := readFloats(t, doc, bin, prim.Attributes["TEXCOORD_0"])
+
+	if !slices.Equal(positions[9:12], positions[0:3]) || slices.Equal(texcoords[6:8], texcoords[0:2]) {
+		t.Errorf("vertex 3 = %v %v, want position 0 with another texture coordinate", positions[9:12], texcoords[6:8])
+	}
+
+	if prim.Indices == nil || doc.Accessors[*prim.Indices].Count != 6 {
+		t.Errorf("indices = %v, want 6 indices", prim.Indices)
+	}
+
+	mat := doc.Materials[0]
+	if factor := mat.PBRMetallicRoughness.BaseColorFactor; factor == nil || *factor != [4]float32{1, 0, 0, 0.5} {
+		t.Errorf("base color factor = %v, want [1 0 0 0.5]", factor)
+	}
+
+	if mat.AlphaMode != "BLEND" {
+		t.Errorf("alpha mode = %q, want BLEND", mat.AlphaMode)
+	}
+}
+
+func TestWriteFileBuffer(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "model.gltf")
+	if err := WriteFile(filename, quadFile()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Buffers []struct {
+			URI        string
+			ByteLength int
+		}
+	}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	bin, err := os.ReadFile(filepath.Join(filepath.Dir(filename), "model.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Buffers) != 1 || doc.Buffers[0].URI != "model.bin" || doc.Buffers[0].ByteLength != len(bin) {
+		t.Errorf("buffers = %+v, want model.bin of %d bytes", doc.Buffers, len(bin))
+	}
+}
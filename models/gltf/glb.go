@@ -0,0 +1,147 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/PeterHackz/conv3d/models/internal/atomicfile"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+// binary glTF container
+//
+// reference: https://registry.khronos.org/glTF/specs/2.0/glTF-2.0.html#binary-gltf-layout
+
+const (
+	glbMagic     = 0x46546C67 // glTF
+	glbVersion   = 2
+	glbChunkJSON = 0x4E4F534A // JSON
+	glbChunkBIN  = 0x004E4942 // BIN\0
+)
+
+var (
+	// ErrInvalidGLB the file starts with the glTF magic but its header or chunks are malformed
+	ErrInvalidGLB = errors.New("invalid glb file")
+)
+
+// IsGLB reports if data starts with the binary glTF magic
+func IsGLB(data []byte) bool {
+	return len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic
+}
+
+// DecodeGLB unpacks a GLB file into its document and embedded BIN chunk (nil if there is none)
+func DecodeGLB(data []byte) (*Document, []byte, error) {
+	if len(data) < 20 || !IsGLB(data) {
+		return nil, nil, ErrInvalidGLB
+	}
+
+	if binary.LittleEndian.Uint32(data[4:]) != glbVersion {
+		return nil, nil, ErrInvalidGLB
+	}
+
+	length := int(binary.LittleEndian.Uint32(data[8:]))
+	if length > len(data) {
+		return nil, nil, ErrInvalidGLB
+	}
+	data = data[:length]
+
+	var (
+		doc *Document
+		bin []byte
+	)
+
+	for offset := 12; offset+8 <= len(data); {
+		chunkLength := int(binary.LittleEndian.Uint32(data[offset:]))
+		chunkType := binary.LittleEndian.Uint32(data[offset+4:])
+		offset += 8
+
+		if offset+chunkLength > len(data) {
+			return nil, nil, ErrInvalidGLB
+		}
+		chunk := data[offset : offset+chunkLength]
+		offset += chunkLength
+
+		switch chunkType {
+		case glbChunkJSON:
+			doc = new(Document)
+			if err := json.Unmarshal(chunk, doc); err != nil {
+				return nil, nil, err
+			}
+		case glbChunkBIN:
+			if bin == nil {
+				bin = chunk
+			}
+		}
+		// unknown chunks must be ignored
+	}
+
+	if doc == nil {
+		return nil, nil, ErrInvalidGLB
+	}
+
+	return doc, bin, nil
+}
+
+// EncodeGLB packs the document and its buffer into a single GLB file
+//
+// buffer 0 becomes the embedded BIN chunk, so its uri is dropped
+func EncodeGLB(doc *Document, bin []byte) ([]byte, error) {
+	if len(doc.Buffers) > 0 {
+		doc.Buffers[0].URI = ""
+		doc.Buffers[0].ByteLength = len(bin)
+	}
+
+	jsonChunk, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// chunks must be aligned to 4 bytes, JSON is padded with spaces and BIN with zeros
+	for len(jsonChunk)%4 != 0 {
+		jsonChunk = append(jsonChunk, ' ')
+	}
+
+	binChunk := make([]byte, len(bin), len(bin)+3)
+	copy(binChunk, bin)
+	for len(binChunk)%4 != 0 {
+		binChunk = append(binChunk, 0)
+	}
+
+	length := 12 + 8 + len(jsonChunk)
+	if len(binChunk) > 0 {
+		length += 8 + len(binChunk)
+	}
+
+	out := make([]byte, 0, length)
+	out = binary.LittleEndian.AppendUint32(out, glbMagic)
+	out = binary.LittleEndian.AppendUint32(out, glbVersion)
+	out = binary.LittleEndian.AppendUint32(out, uint32(length))
+
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(jsonChunk)))
+	out = binary.LittleEndian.AppendUint32(out, glbChunkJSON)
+	out = append(out, jsonChunk...)
+
+	if len(binChunk) > 0 {
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(binChunk)))
+		out = binary.LittleEndian.AppendUint32(out, glbChunkBIN)
+		out = append(out, binChunk...)
+	}
+
+	return out, nil
+}
+
+// WriteGLBFile exports file as a single .glb with the buffer embedded
+func WriteGLBFile(filename string, file *scw.File) error {
+	doc, bin, err := Export(file)
+	if err != nil {
+		return err
+	}
+
+	data, err := EncodeGLB(doc, bin)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.WriteFile(filename, data)
+}
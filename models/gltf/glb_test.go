@@ -0,0 +1,88 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+// TestEncodeGLB checks the container layout byte by byte instead of decoding it with DecodeGLB
+func TestEncodeGLB(t *testing.T) {
+	doc := &Document{
+		Asset:   Asset{Version: "2.0"},
+		Buffers: []Buffer{{URI: "model.bin", ByteLength: 5}},
+	}
+	bin := []byte{1, 2, 3, 4, 5}
+
+	data, err := EncodeGLB(doc, bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) < 20 || string(data[:4]) != "glTF" {
+		t.Fatalf("header = %q, want the glTF magic", data[:min(len(data), 4)])
+	}
+
+	if version := binary.LittleEndian.Uint32(data[4:]); version != 2 {
+		t.Errorf("version = %d, want 2", version)
+	}
+
+	if length := binary.LittleEndian.Uint32(data[8:]); int(length) != len(data) {
+		t.Errorf("length = %d, want %d", length, len(data))
+	}
+
+	jsonLength := int(binary.LittleEndian.Uint32(data[12:]))
+	if chunkType := binary.LittleEndian.Uint32(data[16:]); chunkType != 0x4E4F534A {
+		t.Errorf("first chunk type = %#x, want JSON", chunkType)
+	}
+
+	if jsonLength%4 != 0 || 20+jsonLength+8 > len(data) {
+		t.Fatalf("JSON chunk length = %d, want a multiple of 4 within the file", jsonLength)
+	}
+
+	jsonChunk := data[20 : 20+jsonLength]
+	trimmed := bytes.TrimRight(jsonChunk, " ")
+	if len(jsonChunk)-len(trimmed) > 3 {
+		t.Errorf("JSON chunk is padded with %d spaces, want at most 3", len(jsonChunk)-len(trimmed))
+	}
+
+	var decoded struct {
+		Buffers []map[string]any
+	}
+	if err = json.Unmarshal(trimmed, &decoded); err != nil {
+		t.Fatalf("JSON chunk: %v", err)
+	}
+
+	// the embedded buffer has no uri
+	if len(decoded.Buffers) != 1 || decoded.Buffers[0]["uri"] != nil || decoded.Buffers[0]["byteLength"] != float64(5) {
+		t.Errorf("buffers = %v, want a single buffer of 5 bytes without uri", decoded.Buffers)
+	}
+
+	offset := 20 + jsonLength
+	binLength := int(binary.LittleEndian.Uint32(data[offset:]))
+	if chunkType := binary.LittleEndian.Uint32(data[offset+4:]); chunkType != 0x004E4942 {
+		t.Errorf("second chunk type = %#x, want BIN", chunkType)
+	}
+
+	if binLength != 8 || offset+8+binLength != len(data) {
+		t.Fatalf("BIN chunk length = %d, want 8 ending the file", binLength)
+	}
+
+	if binChunk := data[offset+8:]; !bytes.Equal(binChunk, []byte{1, 2, 3, 4, 5, 0, 0, 0}) {
+		t.Errorf("BIN chunk = %v, want the buffer padded with zeros", binChunk)
+	}
+}
+
+func TestEncodeGLBWithoutBuffer(t *testing.T) {
+	data, err := EncodeGLB(&Document{Asset: Asset{Version: "2.0"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the BIN chunk is optional and must be left out when it would be empty
+	jsonLength := int(binary.LittleEndian.Uint32(data[12:]))
+	if 20+jsonLength != len(data) || int(binary.LittleEndian.Uint32(data[8:])) != len(data) {
+		t.Errorf("glb of %d bytes has a JSON chunk of %d bytes, want only the JSON chunk", len(data), jsonLength)
+	}
+}
@@ -0,0 +1,187 @@
+package gltf
+
+import "encoding/json"
+
+// glTF 2.0 document, only the parts we need are mapped
+//
+// reference: https://registry.khronos.org/glTF/specs/2.0/glTF-2.0.html
+
+const (
+	ComponentByte          = 5120
+	ComponentUnsignedByte  = 5121
+	ComponentShort         = 5122
+	ComponentUnsignedShort = 5123
+	ComponentUnsignedInt   = 5125
+	ComponentFloat         = 5126
+)
+
+const (
+	TargetArrayBuffer        = 34962
+	TargetElementArrayBuffer = 34963
+)
+
+const (
+	TypeScalar = "SCALAR"
+	TypeVec2   = "VEC2"
+	TypeVec3   = "VEC3"
+	TypeVec4   = "VEC4"
+	TypeMat4   = "MAT4"
+)
+
+type Document struct {
+	Asset       Asset        `json:"asset"`
+	Scene       *int         `json:"scene,omitempty"`
+	Scenes      []Scene      `json:"scenes,omitempty"`
+	Nodes       []Node       `json:"nodes,omitempty"`
+	Meshes      []Mesh       `json:"meshes,omitempty"`
+	Materials   []Material   `json:"materials,omitempty"`
+	Textures    []Texture    `json:"textures,omitempty"`
+	Images      []Image      `json:"images,omitempty"`
+	Cameras     []Camera     `json:"cameras,omitempty"`
+	Skins       []Skin       `json:"skins,omitempty"`
+	Animations  []Animation  `json:"animations,omitempty"`
+	Accessors   []Accessor   `json:"accessors,omitempty"`
+	BufferViews []BufferView `json:"bufferViews,omitempty"`
+	Buffers     []Buffer     `json:"buffers,omitempty"`
+}
+
+type Asset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+type Scene struct {
+	Name  string `json:"name,omitempty"`
+	Nodes []int  `json:"nodes,omitempty"`
+}
+
+type Node struct {
+	Name        string       `json:"name,omitempty"`
+	Children    []int        `json:"children,omitempty"`
+	Mesh        *int         `json:"mesh,omitempty"`
+	Camera      *int         `json:"camera,omitempty"`
+	Skin        *int         `json:"skin,omitempty"`
+	Matrix      *[16]float32 `json:"matrix,omitempty"`
+	Rotation    *[4]float32  `json:"rotation,omitempty"`
+	Translation *[3]float32  `json:"translation,omitempty"`
+	Scale       *[3]float32  `json:"scale,omitempty"`
+}
+
+type Mesh struct {
+	Name       string      `json:"name,omitempty"`
+	Primitives []Primitive `json:"primitives"`
+}
+
+type Primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   *int           `json:"material,omitempty"`
+	Mode       *int           `json:"mode,omitempty"`
+}
+
+type Material struct {
+	Name                 string                `json:"name,omitempty"`
+	PBRMetallicRoughness *PBRMetallicRoughness `json:"pbrMetallicRoughness,omitempty"`
+	NormalTexture        *TextureInfo          `json:"normalTexture,omitempty"`
+	EmissiveTexture      *TextureInfo          `json:"emissiveTexture,omitempty"`
+	EmissiveFactor       *[3]float32           `json:"emissiveFactor,omitempty"`
+	AlphaMode            string                `json:"alphaMode,omitempty"`
+	DoubleSided          bool                  `json:"doubleSided,omitempty"`
+}
+
+type PBRMetallicRoughness struct {
+	BaseColorFactor  *[4]float32  `json:"baseColorFactor,omitempty"`
+	BaseColorTexture *TextureInfo `json:"baseColorTexture,omitempty"`
+	MetallicFactor   *float32     `json:"metallicFactor,omitempty"`
+	RoughnessFactor  *float32     `json:"roughnessFactor,omitempty"`
+}
+
+type TextureInfo struct {
+	Index    int `json:"index"`
+	TexCoord int `json:"texCoord,omitempty"`
+}
+
+type Texture struct {
+	Source *int `json:"source,omitempty"`
+}
+
+type Image struct {
+	Name       string `json:"name,omitempty"`
+	URI        string `json:"uri,omitempty"`
+	MimeType   string `json:"mimeType,omitempty"`
+	BufferView *int   `json:"bufferView,omitempty"`
+}
+
+type Camera struct {
+	Name        string       `json:"name,omitempty"`
+	Type        string       `json:"type"`
+	Perspective *Perspective `json:"perspective,omitempty"`
+}
+
+type Perspective struct {
+	AspectRatio float32  `json:"aspectRatio,omitempty"`
+	Yfov        float32  `json:"yfov"`
+	Zfar        *float32 `json:"zfar,omitempty"`
+	Znear       float32  `json:"znear"`
+}
+
+type Skin struct {
+	Name                string `json:"name,omitempty"`
+	InverseBindMatrices *int   `json:"inverseBindMatrices,omitempty"`
+	Skeleton            *int   `json:"skeleton,omitempty"`
+	Joints              []int  `json:"joints"`
+}
+
+type Animation struct {
+	Name     string             `json:"name,omitempty"`
+	Channels []AnimationChannel `json:"channels"`
+	Samplers []AnimationSampler `json:"samplers"`
+}
+
+type AnimationChannel struct {
+	Sampler int           `json:"sampler"`
+	Target  ChannelTarget `json:"target"`
+}
+
+type ChannelTarget struct {
+	Node *int   `json:"node,omitempty"`
+	Path string `json:"path"`
+}
+
+type AnimationSampler struct {
+	Input         int    `json:"input"`
+	Interpolation string `json:"interpolation,omitempty"`
+	Output        int    `json:"output"`
+}
+
+type Accessor struct {
+	BufferView    *int      `json:"bufferView,omitempty"`
+	ByteOffset    int       `json:"byteOffset,omitempty"`
+	ComponentType int       `json:"componentType"`
+	Normalized    bool      `json:"normalized,omitempty"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+
+	// kept raw only to detect them, see ErrSparseAccessor
+	Sparse json.RawMessage `json:"sparse,omitempty"`
+}
+
+type BufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset,omitempty"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride,omitempty"`
+	Target     int `json:"target,omitempty"`
+}
+
+type Buffer struct {
+	URI        string `json:"uri,omitempty"`
+	ByteLength int    `json:"byteLength"`
+}
+
+// ptr is a small helper for the optional (pointer) fields of the document
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,942 @@
+package gltf
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+type importer struct {
+	doc     *Document
+	buffers [][]byte
+	file    *scw.File
+
+	nodeNames     []string
+	nodeIndex     []int // glTF node -> scw node
+	materialNames []string
+	cameraNames   []string // empty for cameras that can not be imported
+
+	geometries    map[[2]int]*scw.Geometry // (mesh, skin) -> geometry
+	geometryNames map[string]bool
+}
+
+// transform is the local transform of a node, rotation is (x, y, z, w)
+type transform struct {
+	translation [3]float64
+	rotation    [4]float64
+	scale       [3]float64
+}
+
+func (t *transform) keyFrame(id uint16) scw.KeyFrame {
+	return scw.KeyFrame{
+		ID: id,
+		Rotation: scw.Quaternion{
+			Vector3: scw.Vector3{X: float32(t.rotation[0]), Y: float32(t.rotation[1]), Z: float32(t.rotation[2])},
+			W:       float32(t.rotation[3]),
+		},
+		Translation: scw.Vector3{X: float32(t.translation[0]), Y: float32(t.translation[1]), Z: float32(t.translation[2])},
+		Scale:       scw.Vector3{X: float32(t.scale[0]), Y: float32(t.scale[1]), Z: float32(t.scale[2])},
+	}
+}
+
+// IsGLTF reports if data is a glTF JSON document, which is the only JSON with a top level asset
+func IsGLTF(data []byte) bool {
+	var doc struct {
+		Asset json.RawMessage `json:"asset"`
+	}
+	return json.Unmarshal(data, &doc) == nil && doc.Asset != nil
+}
+
+// Decode parses a .gltf (JSON) or .glb document, bin is the embedded GLB buffer if any
+func Decode(data []byte) (*Document, []byte, error) {
+	if IsGLB(data) {
+		return DecodeGLB(data)
+	}
+
+	doc := new(Document)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, nil, err
+	}
+
+	return doc, nil, nil
+}
+
+// ReadFile imports a .gltf or .glb file, external buffers are resolved relatively to it
+func ReadFile(filename string) (*scw.File, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Import(data, filepath.Dir(filename))
+}
+
+// Import builds a version 2 scw File from a glTF or GLB document
+//
+// dir is the directory external buffers are loaded from, only the first animation is imported
+// since scw files hold a single timeline
+func Import(data []byte, dir string) (*scw.File, error) {
+	doc, bin, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("unsupported glTF version: %s", doc.Asset.Version)
+	}
+
+	buffers, err := loadBuffers(doc, bin, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &scw.File{}
+	file.Header = scw.Header{
+		Version:   2,
+		FrameRate: scw.DefaultFrameRate,
+	}
+
+	im := &importer{
+		doc:           doc,
+		buffers:       buffers,
+		file:          file,
+		geometries:    make(map[[2]int]*scw.Geometry),
+		geometryNames: make(map[string]bool),
+	}
+
+	im.importMaterials()
+	im.importCameras()
+
+	if err = im.importNodes(); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Animations) > 0 {
+		if err = im.importAnimation(&doc.Animations[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+func (im *importer) texturePath(info *TextureInfo) (string, bool) {
+	if info == nil || info.Index < 0 || info.Index >= len(im.doc.Textures) {
+		return "", false
+	}
+
+	source := im.doc.Textures[info.Index].Source
+	if source == nil || *source < 0 || *source >= len(im.doc.Images) {
+		return "", false
+	}
+
+	img := &im.doc.Images[*source]
+	if len(img.URI) != 0 && !strings.HasPrefix(img.URI, "data:") {
+		if path, err := url.PathUnescape(img.URI); err == nil {
+			return path, true
+		}
+		return img.URI, true
+	}
+
+	// embedded images can not be referenced by scw materials, keep a name so they can be extracted by hand
+	if len(img.Name) != 0 {
+		return img.Name, true
+	}
+	return fmt.Sprintf("image_%d", *source), true
+}
+
+func (im *importer) importMaterials() {
+	used := make(map[string]bool)
+
+	for i := range im.doc.Materials {
+		mat := &im.doc.Materials[i]
+
+		m := &scw.Material{
+			SCWFile: im.file,
+			Name:    scw.UniqueName(mat.Name, fmt.Sprintf("material_%d", i), used),
+		}
+		im.materialNames = append(im.materialNames, m.Name)
+
+		vars := &m.Variables
+		vars.Opacity = 1
+
+		diffuse := [4]float32{1, 1, 1, 1}
+		if pbr := mat.PBRMetallicRoughness; pbr != nil {
+			if pbr.BaseColorFactor != nil {
+				diffuse = *pbr.BaseColorFactor
+			}
+			if path, ok := im.texturePath(pbr.BaseColorTexture); ok {
+				vars.Diffuse.UseText2D = true
+				vars.Diffuse.Texture2D = path
+			}
+		}
+
+		if !vars.Diffuse.UseText2D {
+			vars.Diffuse.Color = scw.RGBAFromFloats(diffuse[:])
+		}
+
+		if mat.AlphaMode == "BLEND" {
+			vars.Opacity = diffuse[3]
+		}
+
+		if path, ok := im.texturePath(mat.NormalTexture); ok {
+			vars.NormalTex2D = path
+		}
+
+		if path, ok := im.texturePath(mat.EmissiveTexture); ok {
+			vars.Emission.UseText2D = true
+			vars.Emission.Texture2D = path
+		} else if mat.EmissiveFactor != nil {
+			factor := mat.EmissiveFactor
+			vars.Emission.Color = scw.RGBAFromFloats(factor[:3])
+		}
+
+		im.file.Materials = append(im.file.Materials, m)
+	}
+}
+
+func (im *importer) importCameras() {
+	used := make(map[string]bool)
+
+	im.cameraNames = make([]string, len(im.doc.Cameras))
+
+	for i := range im.doc.Cameras {
+		cam := &im.doc.Cameras[i]
+
+		// scw only has perspective cameras
+		if cam.Type != "perspective" || cam.Perspective == nil {
+			continue
+		}
+
+		p := cam.Perspective
+
+		camera := &scw.Camera3D{
+			Name:        scw.UniqueName(cam.Name, fmt.Sprintf("camera_%d", i), used),
+			Yfov:        p.Yfov * 180 / math.Pi,
+			AspectRatio: p.AspectRatio,
+			ZNear:       p.Znear,
+		}
+
+		if p.AspectRatio > 0 {
+			camera.Xfov = float32(2 * math.Atan(math.Tan(float64(p.Yfov)/2)*float64(p.AspectRatio)) * 180 / math.Pi)
+		}
+
+		if p.Zfar != nil {
+			camera.ZFar = *p.Zfar
+		}
+
+		im.cameraNames[i] = camera.Name
+		im.file.Cameras = append(im.file.Cameras, camera)
+	}
+}
+
+func restTransform(node *Node) transform {
+	t := transform{
+		rotation: [4]float64{0, 0, 0, 1},
+		scale:    [3]float64{1, 1, 1},
+	}
+
+	if node.Matrix != nil {
+		// glTF matrices are column major
+		var m scw.Matrix4x4
+		for r := range 4 {
+			for c := range 4 {
+				m[r][c] = node.Matrix[c*4+r]
+			}
+		}
+
+		translation, rotation, scale := m.Decompose()
+		t.translation = [3]float64{float64(translation.X), float64(translation.Y), float64(translation.Z)}
+		t.rotation = [4]float64{float64(rotation.X), float64(rotation.Y), float64(rotation.Z), float64(rotation.W)}
+		t.scale = [3]float64{float64(scale.X), float64(scale.Y), float64(scale.Z)}
+		return t
+	}
+
+	if node.Translation != nil {
+		for i, v := range node.Translation {
+			t.translation[i] = float64(v)
+		}
+	}
+
+	if node.Rotation != nil {
+		for i, v := range node.Rotation {
+			t.rotation[i] = float64(v)
+		}
+	}
+
+	if node.Scale != nil {
+		for i, v := range node.Scale {
+			t.scale[i] = float64(v)
+		}
+	}
+
+	return t
+}
+
+func (im *importer) importNodes() error {
+	nodes := im.doc.Nodes
+
+	used := make(map[string]bool)
+	im.nodeNames = make([]string, len(nodes))
+	for i := range nodes {
+		im.nodeNames[i] = scw.UniqueName(nodes[i].Name, fmt.Sprintf("node_%d", i), used)
+	}
+
+	parents := make([]int, len(nodes))
+	for i := range parents {
+		parents[i] = -1
+	}
+
+	for i := range nodes {
+		for _, child := range nodes[i].Children {
+			if child < 0 || child >= len(nodes) {
+				return fmt.Errorf("node %s has an invalid child: %d", im.nodeNames[i], child)
+			}
+			if parents[child] != -1 {
+				return fmt.Errorf("node %s has more than one parent", im.nodeNames[child])
+			}
+			parents[child] = i
+		}
+	}
+
+	// parents are listed before their children, starting with the default scene
+	var (
+		order   []int
+		visited = make([]bool, len(nodes))
+		visit   func(i int)
+	)
+
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		order = append(order, i)
+		for _, child := range nodes[i].Children {
+			visit(child)
+		}
+	}
+
+	scene := 0
+	if im.doc.Scene != nil {
+		scene = *im.doc.Scene
+	}
+
+	if scene >= 0 && scene < len(im.doc.Scenes) {
+		for _, root := range im.doc.Scenes[scene].Nodes {
+			if root >= 0 && root < len(nodes) && parents[root] == -1 {
+				visit(root)
+			}
+		}
+	}
+
+	for i := range nodes {
+		if parents[i] == -1 {
+			visit(i)
+		}
+	}
+
+	im.nodeIndex = make([]int, len(nodes))
+
+	for _, i := range order {
+		node := scw.Node{
+			SCWFile: im.file,
+			Name:    im.nodeNames[i],
+		}
+
+		if parents[i] != -1 {
+			node.ParentName = im.nodeNames[parents[i]]
+		}
+
+		rest := restTransform(&nodes[i])
+		node.Frames = []scw.KeyFrame{rest.keyFrame(0)}
+
+		if err := im.importInstances(&nodes[i], &node); err != nil {
+			return err
+		}
+
+		im.nodeIndex[i] = len(im.file.Nodes)
+		im.file.Nodes = append(im.file.Nodes, node)
+	}
+
+	return nil
+}
+
+func (im *importer) importInstances(in *Node, node *scw.Node) error {
+	if in.Mesh != nil {
+		skin := -1
+		if in.Skin != nil {
+			skin = *in.Skin
+		}
+
+		geom, err := im.geometry(*in.Mesh, skin)
+		if err != nil {
+			return err
+		}
+
+		instance := scw.NodeInstance{
+			Type:   "GEOM",
+			Target: geom.Name,
+		}
+
+		if skin != -1 {
+			instance.Type = "CONT"
+		}
+
+		bound := make(map[string]bool)
+		for _, prim := range geom.Materials {
+			if len(prim.Name) == 0 || bound[prim.Name] {
+				continue
+			}
+			bound[prim.Name] = true
+			instance.Materials = append(instance.Materials, scw.InstanceMaterial{Name: prim.Name, Target: prim.Name})
+		}
+
+		node.Instances = append(node.Instances, instance)
+	}
+
+	if in.Camera != nil {
+		if *in.Camera < 0 || *in.Camera >= len(im.cameraNames) {
+			return fmt.Errorf("node %s references an invalid camera: %d", node.Name, *in.Camera)
+		}
+
+		if name := im.cameraNames[*in.Camera]; len(name) != 0 {
+			node.Instances = append(node.Instances, scw.NodeInstance{
+				Type:   "CAME",
+				Target: name,
+			})
+		}
+	}
+
+	return nil
+}
+
+func (im *importer) geometry(mesh, skin int) (*scw.Geometry, error) {
+	key := [2]int{mesh, skin}
+	if geom, ok := im.geometries[key]; ok {
+		return geom, nil
+	}
+
+	if mesh < 0 || mesh >= len(im.doc.Meshes) {
+		return nil, fmt.Errorf("invalid mesh index: %d", mesh)
+	}
+
+	if skin < -1 || skin >= len(im.doc.Skins) {
+		return nil, fmt.Errorf("invalid skin index: %d", skin)
+	}
+
+	m := &im.doc.Meshes[mesh]
+	name := scw.UniqueName(m.Name, fmt.Sprintf("mesh_%d", mesh), im.geometryNames)
+
+	geom, err := im.importGeometry(m, name, skin)
+	if err != nil {
+		return nil, err
+	}
+
+	im.geometries[key] = geom
+	im.file.Geometries = append(im.file.Geometries, geom)
+	return geom, nil
+}
+
+// sourceName maps a glTF attribute to the scw source array name and set
+func sourceName(attribute string) (string, byte) {
+	for _, prefix := range []string{"TEXCOORD_", "COLOR_"} {
+		if set, found := strings.CutPrefix(attribute, prefix); found {
+			n, _ := strconv.Atoi(set)
+			return strings.TrimSuffix(prefix, "_"), byte(n)
+		}
+	}
+
+	// application specific attributes, the exporter prefixes unknown scw sources with an underscore
+	return strings.TrimPrefix(attribute, "_"), 0
+}
+
+func attributeRank(attribute string) int {
+	switch {
+	case attribute == "POSITION":
+		return 0
+	case attribute == "NORMAL":
+		return 1
+	case strings.HasPrefix(attribute, "TEXCOORD_"):
+		return 2
+	case strings.HasPrefix(attribute, "COLOR_"):
+		return 3
+	case strings.HasPrefix(attribute, "_"):
+		return 4
+	default:
+		// TANGENT has no scw equivalent, JOINTS/WEIGHTS become skin weights
+		return -1
+	}
+}
+
+// sourceAttributes returns the attributes that become scw source arrays, in the usual scw order
+func sourceAttributes(attributes map[string]int) []string {
+	var out []string
+	for attribute := range attributes {
+		if attributeRank(attribute) != -1 {
+			out = append(out, attribute)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		ri, rj := attributeRank(out[i]), attributeRank(out[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return out[i] < out[j]
+	})
+
+	return out
+}
+
+// triangulate converts the indices of a primitive to a triangle list
+func triangulate(indices []uint32, mode int) ([]uint32, error) {
+	switch mode {
+	case 4: // TRIANGLES
+		if len(indices)%3 != 0 {
+			return nil, fmt.Errorf("triangles primitive has %d indices", len(indices))
+		}
+		return indices, nil
+	case 5: // TRIANGLE_STRIP
+		var out []uint32
+		for i := 0; i+2 < len(indices); i++ {
+			if i%2 == 0 {
+				out = append(out, indices[i], indices[i+1], indices[i+2])
+			} else {
+				out = append(out, indices[i+1], indices[i], indices[i+2])
+			}
+		}
+		return out, nil
+	case 6: // TRIANGLE_FAN
+		var out []uint32
+		for i := 1; i+1 < len(indices); i++ {
+			out = append(out, indices[0], indices[i], indices[i+1])
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported primitive mode: %d", mode)
+	}
+}
+
+func (im *importer) importGeometry(mesh *Mesh, name string, skin int) (*scw.Geometry, error) {
+	if len(mesh.Primitives) == 0 {
+		return nil, fmt.Errorf("mesh %s has no primitives", name)
+	}
+
+	attributes := sourceAttributes(mesh.Primitives[0].Attributes)
+	if len(attributes) == 0 || attributes[0] != "POSITION" {
+		return nil, fmt.Errorf("mesh %s has no POSITION attribute", name)
+	}
+
+	geom := &scw.Geometry{
+		SCWFile: im.file,
+		Name:    name,
+	}
+
+	var (
+		sources    = make([][]float64, len(attributes))
+		strides    = make([]int, len(attributes))
+		weights    []scw.Weight
+		vertexBase uint32
+	)
+
+	for p := range mesh.Primitives {
+		prim := &mesh.Primitives[p]
+
+		count := -1
+		for a, attribute := range attributes {
+			accessor, ok := prim.Attributes[attribute]
+			if !ok {
+				return nil, fmt.Errorf("mesh %s: primitive %d has no %s attribute", name, p, attribute)
+			}
+
+			values, components, err := readAccessor(im.doc, im.buffers, accessor)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %s: %w", name, err)
+			}
+
+			if attribute == "POSITION" && components != 3 {
+				return nil, fmt.Errorf("mesh %s: POSITION accessor %d is not a VEC3", name, accessor)
+			}
+
+			if p == 0 {
+				strides[a] = components
+			} else if strides[a] != components {
+				return nil, fmt.Errorf("mesh %s: %s has a different type across primitives", name, attribute)
+			}
+
+			if n := len(values) / components; count == -1 {
+				count = n
+			} else if n != count {
+				return nil, fmt.Errorf("mesh %s: primitive %d attributes have different lengths", name, p)
+			}
+
+			sources[a] = append(sources[a], values...)
+		}
+
+		if skin != -1 {
+			primWeights, err := im.skinWeights(prim, count)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %s: %w", name, err)
+			}
+			weights = append(weights, primWeights...)
+		}
+
+		var indices []uint32
+		if prim.Indices != nil {
+			values, _, err := readAccessor(im.doc, im.buffers, *prim.Indices)
+			if err != nil {
+				return nil, fmt.Errorf("mesh %s: %w", name, err)
+			}
+			indices = make([]uint32, len(values))
+			for i, v := range values {
+				indices[i] = uint32(v)
+			}
+		} else {
+			indices = make([]uint32, count)
+			for i := range indices {
+				indices[i] = uint32(i)
+			}
+		}
+
+		mode := 4
+		if prim.Mode != nil {
+			mode = *prim.Mode
+		}
+
+		triangles, err := triangulate(indices, mode)
+		if err != nil {
+			return nil, fmt.Errorf("mesh %s: %w", name, err)
+		}
+
+		indexArray := scw.IndexArray{
+			InputsCount:    byte(len(attributes)),
+			TrianglesCount: uint32(len(triangles) / 3),
+		}
+
+		if prim.Material != nil && *prim.Material >= 0 && *prim.Material < len(im.materialNames) {
+			indexArray.Name = im.materialNames[*prim.Material]
+		}
+
+		// every source is its own input, they all share the glTF vertex index
+		indexArray.IndexBuffer = make([]uint32, 0, len(triangles)*len(attributes))
+		for _, idx := range triangles {
+			if int(idx) >= count {
+				return nil, fmt.Errorf("mesh %s: primitive %d index %d is out of range", name, p, idx)
+			}
+			for range attributes {
+				indexArray.IndexBuffer = append(indexArray.IndexBuffer, vertexBase+idx)
+			}
+		}
+
+		geom.Materials = append(geom.Materials, indexArray)
+		vertexBase += uint32(count)
+	}
+
+	indexBufferSize := byte(4)
+	if vertexBase <= math.MaxUint8+1 {
+		indexBufferSize = 1
+	} else if vertexBase <= math.MaxUint16+1 {
+		indexBufferSize = 2
+	}
+
+	for i := range geom.Materials {
+		geom.Materials[i].IndexBufferSize = indexBufferSize
+	}
+
+	for a, attribute := range attributes {
+		sourceName, set := sourceName(attribute)
+		geom.Vertices = append(geom.Vertices, scw.SourceArray{
+			Name:        sourceName,
+			Index:       byte(a),
+			SourceIndex: set,
+			Stride:      byte(strides[a]),
+			Scale:       scw.QuantizationScale(sources[a]),
+			Data:        sources[a],
+		})
+	}
+
+	if skin != -1 {
+		if err := im.importSkin(geom, &im.doc.Skins[skin]); err != nil {
+			return nil, err
+		}
+		geom.SkinWeights = weights
+	}
+
+	return geom, nil
+}
+
+// skinWeights reads JOINTS_0 and WEIGHTS_0, scw only keeps 4 influences per vertex
+func (im *importer) skinWeights(prim *Primitive, count int) ([]scw.Weight, error) {
+	jointsAccessor, ok := prim.Attributes["JOINTS_0"]
+	if !ok {
+		return nil, fmt.Errorf("skinned primitive has no JOINTS_0 attribute")
+	}
+
+	weightsAccessor, ok := prim.Attributes["WEIGHTS_0"]
+	if !ok {
+		return nil, fmt.Errorf("skinned primitive has no WEIGHTS_0 attribute")
+	}
+
+	joints, _, err := readAccessor(im.doc, im.buffers, jointsAccessor)
+	if err != nil {
+		return nil, err
+	}
+
+	values, _, err := readAccessor(im.doc, im.buffers, weightsAccessor)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(joints) != 4*count || len(values) != 4*count {
+		return nil, fmt.Errorf("skin attributes do not match the vertices count")
+	}
+
+	weights := make([]scw.Weight, count)
+
+	for v := range weights {
+		sum := 0.0
+		for i := range 4 {
+			sum += values[v*4+i]
+		}
+
+		for i := range 4 {
+			joint := joints[v*4+i]
+			if joint > math.MaxUint8 {
+				return nil, fmt.Errorf("joint index %d does not fit in scw skin weights", int(joint))
+			}
+			weights[v].Joints[i] = byte(joint)
+
+			if sum > 0 {
+				weights[v].Weights[i] = uint16(math.Round(values[v*4+i] / sum * math.MaxUint16))
+			}
+		}
+	}
+
+	return weights, nil
+}
+
+func (im *importer) importSkin(geom *scw.Geometry, skin *Skin) error {
+	var matrices []float64
+	if skin.InverseBindMatrices != nil {
+		var err error
+		if matrices, _, err = readAccessor(im.doc, im.buffers, *skin.InverseBindMatrices); err != nil {
+			return fmt.Errorf("skin of %s: %w", geom.Name, err)
+		}
+		if len(matrices) != 16*len(skin.Joints) {
+			return fmt.Errorf("skin of %s: inverse bind matrices do not match its joints", geom.Name)
+		}
+	}
+
+	identity := scw.Identity()
+
+	geom.HasBindMatrix = true
+	geom.BindMatrix = identity
+
+	for i, joint := range skin.Joints {
+		if joint < 0 || joint >= len(im.nodeNames) {
+			return fmt.Errorf("skin of %s has an invalid joint: %d", geom.Name, joint)
+		}
+		geom.Skins.Joints = append(geom.Skins.Joints, im.nodeNames[joint])
+
+		matrix := identity
+		if matrices != nil {
+			// glTF matrices are column major
+			for r := range 4 {
+				for c := range 4 {
+					matrix[r][c] = float32(matrices[i*16+c*4+r])
+				}
+			}
+		}
+		geom.Skins.InverseBindMatrices = append(geom.Skins.InverseBindMatrices, matrix)
+	}
+
+	return nil
+}
+
+// track is one sampled property of an animated node
+type track struct {
+	times         []float64
+	values        []float64
+	components    int
+	interpolation string
+	rotation      bool
+}
+
+func (t *track) value(k int) []float64 {
+	if t.interpolation == "CUBICSPLINE" {
+		// (in tangent, value, out tangent) triplets, only the values are kept
+		k = k*3 + 1
+	}
+	return t.values[k*t.components : (k+1)*t.components]
+}
+
+// sample evaluates the track at time, cubic splines are approximated linearly
+func (t *track) sample(time float64, out []float64) {
+	last := len(t.times) - 1
+
+	if time <= t.times[0] {
+		copy(out, t.value(0))
+		return
+	}
+
+	if time >= t.times[last] {
+		copy(out, t.value(last))
+		return
+	}
+
+	k := sort.SearchFloat64s(t.times, time)
+	if t.times[k] == time {
+		copy(out, t.value(k))
+		return
+	}
+	k-- // times[k] < time < times[k+1]
+
+	if t.interpolation == "STEP" {
+		copy(out, t.value(k))
+		return
+	}
+
+	f := (time - t.times[k]) / (t.times[k+1] - t.times[k])
+	if t.rotation {
+		slerp(t.value(k), t.value(k+1), f, out)
+	} else {
+		lerp(t.value(k), t.value(k+1), f, out)
+	}
+}
+
+type nodeTracks struct {
+	translation, rotation, scale *track
+}
+
+// importAnimation samples the animation at the file frame rate into the nodes key frames
+func (im *importer) importAnimation(anim *Animation) error {
+	tracks := make(map[int]*nodeTracks)
+	duration := 0.0
+
+	for _, channel := range anim.Channels {
+		node := channel.Target.Node
+		if node == nil {
+			continue
+		}
+
+		if *node < 0 || *node >= len(im.doc.Nodes) {
+			return fmt.Errorf("animation channel targets an invalid node: %d", *node)
+		}
+
+		// output components of the animated property
+		var outputComponents int
+		switch channel.Target.Path {
+		case "translation", "scale":
+			outputComponents = 3
+		case "rotation":
+			outputComponents = 4
+		default:
+			// morph target weights have no scw equivalent
+			continue
+		}
+
+		if channel.Sampler < 0 || channel.Sampler >= len(anim.Samplers) {
+			return fmt.Errorf("animation channel has an invalid sampler: %d", channel.Sampler)
+		}
+		sampler := &anim.Samplers[channel.Sampler]
+
+		times, inputComponents, err := readAccessor(im.doc, im.buffers, sampler.Input)
+		if err != nil {
+			return err
+		}
+
+		values, components, err := readAccessor(im.doc, im.buffers, sampler.Output)
+		if err != nil {
+			return err
+		}
+
+		// the tracks are interpolated with the components of their property
+		if inputComponents != 1 || components != outputComponents {
+			return fmt.Errorf("animation sampler %d: %s output is not a VEC%d of SCALAR times", channel.Sampler, channel.Target.Path, outputComponents)
+		}
+
+		if len(times) == 0 {
+			continue
+		}
+
+		t := &track{
+			times:         times,
+			values:        values,
+			components:    components,
+			interpolation: sampler.Interpolation,
+			rotation:      channel.Target.Path == "rotation",
+		}
+
+		expected := len(times) * components
+		if t.interpolation == "CUBICSPLINE" {
+			expected *= 3
+		}
+		if len(values) != expected {
+			return fmt.Errorf("animation sampler %d output does not match its input", channel.Sampler)
+		}
+
+		nt, ok := tracks[*node]
+		if !ok {
+			nt = new(nodeTracks)
+			tracks[*node] = nt
+		}
+
+		switch channel.Target.Path {
+		case "translation":
+			nt.translation = t
+		case "rotation":
+			nt.rotation = t
+		case "scale":
+			nt.scale = t
+		}
+
+		duration = max(duration, times[len(times)-1])
+	}
+
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	rate := float64(im.file.FrameRate)
+	lastFrame := int(math.Round(duration * rate))
+	if lastFrame > math.MaxUint16 {
+		return fmt.Errorf("animation is too long: %d frames", lastFrame)
+	}
+
+	im.file.FirstFrame = 0
+	im.file.LastFrame = uint16(lastFrame)
+
+	for gltfNode, nt := range tracks {
+		rest := restTransform(&im.doc.Nodes[gltfNode])
+
+		frames := make([]scw.KeyFrame, lastFrame+1)
+		for f := range frames {
+			t := rest
+			time := float64(f) / rate
+
+			if nt.translation != nil {
+				nt.translation.sample(time, t.translation[:])
+			}
+			if nt.rotation != nil {
+				nt.rotation.sample(time, t.rotation[:])
+			}
+			if nt.scale != nil {
+				nt.scale.sample(time, t.scale[:])
+			}
+
+			frames[f] = t.keyFrame(uint16(f))
+		}
+
+		im.file.Nodes[im.nodeIndex[gltfNode]].Frames = frames
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package gltf
+
+import (
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models/internal/modeltest"
+)
+
+// importModified exports the animated fixture, modifies its document and imports it back
+func importModified(t *testing.T, modify func(doc *Document)) error {
+	t.Helper()
+
+	doc, bin, err := Export(modeltest.LoadFixture(t, "animated.scw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	modify(doc)
+
+	data, err := EncodeGLB(doc, bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Import(data, "")
+	return err
+}
+
+func TestImportAnimationOutputType(t *testing.T) {
+	if err := importModified(t, func(*Document) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"translation", "rotation", "scale"} {
+		t.Run(path, func(t *testing.T) {
+			err := importModified(t, func(doc *Document) {
+				for _, channel := range doc.Animations[0].Channels {
+					if channel.Target.Path == path {
+						sampler := &doc.Animations[0].Samplers[channel.Sampler]
+						// the times are a SCALAR accessor of the same count
+						sampler.Output = sampler.Input
+						return
+					}
+				}
+				t.Fatalf("no %s channel", path)
+			})
+
+			if err == nil {
+				t.Fatal("a SCALAR output was imported")
+			}
+		})
+	}
+}
+
+func TestImportPositionType(t *testing.T) {
+	err := importModified(t, func(doc *Document) {
+		position := doc.Meshes[0].Primitives[0].Attributes["POSITION"]
+		doc.Accessors[position].Type = TypeVec2
+	})
+
+	if err == nil {
+		t.Fatal("a VEC2 POSITION was imported")
+	}
+}
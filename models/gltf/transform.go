@@ -0,0 +1,40 @@
+package gltf
+
+import "math"
+
+func lerp(a, b []float64, t float64, out []float64) {
+	for i := range out {
+		out[i] = a[i] + (b[i]-a[i])*t
+	}
+}
+
+// slerp interpolates two (x, y, z, w) quaternions taking the shortest path
+func slerp(a, b []float64, t float64, out []float64) {
+	dot := a[0]*b[0] + a[1]*b[1] + a[2]*b[2] + a[3]*b[3]
+
+	sign := 1.0
+	if dot < 0 {
+		dot, sign = -dot, -1
+	}
+
+	wa, wb := 1-t, t*sign
+	// close quaternions are linearly interpolated to avoid dividing by ~0
+	if dot < 0.9995 {
+		theta := math.Acos(dot)
+		sin := math.Sin(theta)
+		wa = math.Sin((1-t)*theta) / sin
+		wb = math.Sin(t*theta) / sin * sign
+	}
+
+	length := 0.0
+	for i := range 4 {
+		out[i] = a[i]*wa + b[i]*wb
+		length += out[i] * out[i]
+	}
+
+	if length = math.Sqrt(length); length > 0 {
+		for i := range 4 {
+			out[i] /= length
+		}
+	}
+}
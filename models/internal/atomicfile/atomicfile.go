@@ -0,0 +1,62 @@
+// Package atomicfile writes files through a temporary file renamed over them, it is shared by
+// models and the format packages writing their outputs (and sidecar files) themselves
+package atomicfile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Create calls write with a buffered writer to a temporary file in the directory of filename,
+// which replaces filename once everything was written: filename is left as it was on errors so it
+// can be the file being converted, write, close and rename errors are returned
+func Create(filename string, write func(w io.Writer) error) (err error) {
+	// replaced files keep their permissions
+	mode := fs.FileMode(0644)
+	if info, statErr := os.Stat(filename); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(out.Name())
+		}
+	}()
+
+	buffered := bufio.NewWriter(out)
+	if err = write(buffered); err != nil {
+		return err
+	}
+
+	if err = buffered.Flush(); err != nil {
+		return err
+	}
+
+	if err = out.Chmod(mode); err != nil {
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(out.Name(), filename)
+}
+
+// WriteFile is Create for data already in memory
+func WriteFile(filename string, data []byte) error {
+	return Create(filename, func(w io.Writer) error {
+		_, err := io.Copy(w, bytes.NewReader(data))
+		return err
+	})
+}
@@ -0,0 +1,107 @@
+// Package modeltest has the helpers shared by the round trip tests of the format packages
+package modeltest
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+// LoadFixture loads a file of the scw testdata from a format package directory
+func LoadFixture(t testing.TB, name string) *scw.File {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("..", "scw", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := scw.New(data)
+	if err = file.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// Corners returns the position of every triangle corner of geometry
+func Corners(t testing.TB, geometry *scw.Geometry) [][3]float64 {
+	t.Helper()
+
+	var position *scw.SourceArray
+	for i := range geometry.Vertices {
+		if geometry.Vertices[i].IsPosition() {
+			position = &geometry.Vertices[i]
+		}
+	}
+	if position == nil {
+		t.Fatalf("geometry %s has no position", geometry.Name)
+	}
+
+	var positions [][3]float64
+	for _, prim := range geometry.Materials {
+		inputs := int(prim.InputsCount)
+		for c := range 3 * int(prim.TrianglesCount) {
+			i := 3 * int(prim.IndexBuffer[c*inputs+int(position.Index)])
+			positions = append(positions, [3]float64(position.Data[i:i+3]))
+		}
+	}
+	return positions
+}
+
+// CompareGeometries checks that imported has the geometries of file, with the same names,
+// skin weights count and triangle corners (within tolerance, formats may store float32 values)
+func CompareGeometries(t testing.TB, file, imported *scw.File, tolerance float64) {
+	t.Helper()
+
+	if len(imported.Geometries) != len(file.Geometries) {
+		t.Fatalf("imported %d geometries, expected %d", len(imported.Geometries), len(file.Geometries))
+	}
+
+	for i, geometry := range file.Geometries {
+		out := imported.Geometries[i]
+		if out.Name != geometry.Name || len(out.SkinWeights) != len(geometry.SkinWeights) {
+			t.Fatalf("geometry %s with %d skin weights imported as %s with %d", geometry.Name, len(geometry.SkinWeights), out.Name, len(out.SkinWeights))
+		}
+
+		expected, positions := Corners(t, geometry), Corners(t, out)
+		if len(positions) != len(expected) {
+			t.Fatalf("geometry %s: %d triangle corners, expected %d", geometry.Name, len(positions), len(expected))
+		}
+		for c := range expected {
+			for k := range 3 {
+				if math.Abs(positions[c][k]-expected[c][k]) > tolerance {
+					t.Fatalf("geometry %s: corner %d at %v, expected %v", geometry.Name, c, positions[c], expected[c])
+				}
+			}
+		}
+	}
+}
+
+// CompareScene checks that imported has the nodes (names, parents and instances count), materials and
+// cameras of file
+func CompareScene(t testing.TB, file, imported *scw.File) {
+	t.Helper()
+
+	if len(imported.Nodes) != len(file.Nodes) || len(imported.Materials) != len(file.Materials) || len(imported.Cameras) != len(file.Cameras) {
+		t.Fatalf("imported %d nodes, %d materials and %d cameras, expected %d, %d and %d",
+			len(imported.Nodes), len(imported.Materials), len(imported.Cameras),
+			len(file.Nodes), len(file.Materials), len(file.Cameras))
+	}
+
+	for i, node := range file.Nodes {
+		if out := imported.Nodes[i]; out.Name != node.Name || out.ParentName != node.ParentName || len(out.Instances) != len(node.Instances) {
+			t.Fatalf("node %s (parent %q, %d instances) imported as %s (parent %q, %d instances)",
+				node.Name, node.ParentName, len(node.Instances), out.Name, out.ParentName, len(out.Instances))
+		}
+	}
+}
+
+// SameRotation reports if a and b are the same rotation once normalized, q and -q included
+func SameRotation(a, b scw.Quaternion) bool {
+	dot := float64(a.X*b.X + a.Y*b.Y + a.Z*b.Z + a.W*b.W)
+	lengths := math.Sqrt(float64(a.X*a.X+a.Y*a.Y+a.Z*a.Z+a.W*a.W) * float64(b.X*b.X+b.Y*b.Y+b.Z*b.Z+b.W*b.W))
+	return math.Abs(math.Abs(dot)/lengths-1) < 1e-5
+}
@@ -1,52 +1,392 @@
 package models
 
 import (
-	"github.com/PeterHackz/conv3d/models/scw"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/PeterHackz/conv3d/models/dae"
+	"github.com/PeterHackz/conv3d/models/gltf"
+	"github.com/PeterHackz/conv3d/models/internal/atomicfile"
+	"github.com/PeterHackz/conv3d/models/obj"
+	"github.com/PeterHackz/conv3d/models/scene"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+var (
+	// ErrUnknownFormat neither the content nor the extension of a file match a registered format
+	ErrUnknownFormat = errors.New("unknown model format")
+	// ErrNotReadable the format has no decoder
+	ErrNotReadable = errors.New("format can not be read")
+	// ErrNotWritable the format has no encoder
+	ErrNotWritable = errors.New("format can not be written")
 )
 
-// Model wraps the models formats
+// Options are passed to every decoder
+type Options struct {
+	// Dir is the directory of the decoded file, used to resolve external files (buffers, materials)
+	Dir string
+	// MinorVersion of v0 scw files, nil detects it from their geometries
+	MinorVersion *int
+	// Strict verifies the checksum of every scw chunk
+	Strict bool
+	// RawSamples keeps the int16 vertex samples of scw files, see scw.LoadOptions
+	RawSamples bool
+	// Warn receives what decoders import with defaults (missing external files...), nil ignores it
+	Warn func(msg string)
+}
+
+// Format is a model format, it is read and written either as an scw File or as a format neutral
+// scene.Scene: formats built on the scene set DecodeScene and EncodeScene, Register derives Decode
+// and Encode from them with Scene.ToSCW and scene.FromSCW
 //
-// support for GLB/GLTF and SCW models is a priority
-type Model interface {
-	Load() error
-	LoadJSON() error
-	Encode() []byte
+// a format can be read only (no Encode) or write only (no Decode)
+type Format struct {
+	Name string
+	// Extensions with their dot, the first one is used for default output file names
+	Extensions []string
+	// Magic reports if data is in this format, nil for formats that can only be detected by extension
+	Magic func(data []byte) bool
+
+	Decode func(data []byte, opts Options) (*scw.File, error)
+	Encode func(filename string, file *scw.File) error
+
+	// DecodeReader decodes the format from a reader instead of the whole file, LoadFromFile uses it
+	// when Magic matches the first MagicSize bytes of the file
+	DecodeReader func(r io.Reader, opts Options) (*scw.File, error)
+
+	// DecodeScene and EncodeScene are set by formats built on the scene, LoadScene and WriteScene
+	// use them without going through an scw File
+	DecodeScene func(data []byte, opts Options) (*scene.Scene, error)
+	EncodeScene func(filename string, s *scene.Scene) error
 }
 
-var formats = map[string]func(data []byte) Model{
-	"scw": func(data []byte) Model {
-		return scw.New(data)
-	},
+// MagicSize is the number of bytes the Magic of formats with a DecodeReader is checked on
+const MagicSize = 512
+
+func (f *Format) CanRead() bool {
+	return f.Decode != nil
 }
 
-// LoadFromFile Loads a model from a file
-//
-// for now, it checks the file type using the extension
-//
-// TODO: use header MAGIC to detect file type
-func LoadFromFile(filename string) (Model, error) {
+func (f *Format) CanWrite() bool {
+	return f.Encode != nil
+}
+
+var formats []Format
+
+// Register adds a format, magics are checked in registration order
+func Register(format Format) error {
+	if len(format.Name) == 0 {
+		return errors.New("format has no name")
+	}
+
+	if decodeScene := format.DecodeScene; decodeScene != nil && format.Decode == nil {
+		format.Decode = func(data []byte, opts Options) (*scw.File, error) {
+			s, err := decodeScene(data, opts)
+			if err != nil {
+				return nil, err
+			}
+			return s.ToSCW()
+		}
+	}
+
+	if encodeScene := format.EncodeScene; encodeScene != nil && format.Encode == nil {
+		format.Encode = func(filename string, file *scw.File) error {
+			s, err := scene.FromSCW(file)
+			if err != nil {
+				return err
+			}
+			return encodeScene(filename, s)
+		}
+	}
+
+	if format.Decode == nil && format.Encode == nil {
+		return fmt.Errorf("format %s has no decoder nor encoder", format.Name)
+	}
+
+	if Lookup(format.Name) != nil {
+		return fmt.Errorf("format %s is already registered", format.Name)
+	}
+
+	formats = append(formats, format)
+	return nil
+}
+
+// Formats returns the registered formats
+func Formats() []Format {
+	return append([]Format(nil), formats...)
+}
+
+// Lookup returns the format registered as name, nil if there is none
+func Lookup(name string) *Format {
+	for i := range formats {
+		if formats[i].Name == name {
+			return &formats[i]
+		}
+	}
+	return nil
+}
+
+// ByExtension returns the format with the longest extension matching filename
+func ByExtension(filename string) (*Format, error) {
+	var (
+		found  *Format
+		length int
+	)
+
+	lower := strings.ToLower(filename)
+	for i := range formats {
+		for _, ext := range formats[i].Extensions {
+			if len(ext) > length && strings.HasSuffix(lower, ext) {
+				found, length = &formats[i], len(ext)
+			}
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, filename)
+	}
+	return found, nil
+}
+
+// Detect returns the format of data, the magic is checked first then the extension of filename
+func Detect(filename string, data []byte) (*Format, error) {
+	for i := range formats {
+		if formats[i].Magic != nil && formats[i].Magic(data) {
+			return &formats[i], nil
+		}
+	}
+
+	return ByExtension(filename)
+}
+
+// LoadFromFile Loads a model from a file and returns its detected format,
+// formats with a DecodeReader are streamed, the others are read at once
+func LoadFromFile(filename string, opts Options) (*scw.File, *Format, error) {
 	file, err := os.Open(filename)
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	opts.Dir = filepath.Dir(filename)
+
+	buffered := bufio.NewReader(file)
+
+	// a short file is not an error here, its head is only shorter than MagicSize
+	head, err := buffered.Peek(MagicSize)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+
+	for i := range formats {
+		format := &formats[i]
+		if format.DecodeReader != nil && format.Magic != nil && format.Magic(head) {
+			model, err := format.DecodeReader(buffered, opts)
+			if err != nil {
+				return nil, format, err
+			}
+			return model, format, nil
+		}
+	}
+
+	data, err := io.ReadAll(buffered)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	format, err := Detect(filename, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !format.CanRead() {
+		return nil, format, fmt.Errorf("%w: %s", ErrNotReadable, format.Name)
+	}
+
+	model, err := format.Decode(data, opts)
+	if err != nil {
+		return nil, format, err
+	}
+
+	return model, format, nil
+}
+
+// LoadScene loads a model as a format neutral scene, formats built on it are decoded to it directly
+// and the others through an scw File, whose unknown chunks and raw nodes are dropped with a warning
+func LoadScene(filename string, opts Options) (*scene.Scene, *Format, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts.Dir = filepath.Dir(filename)
+
+	format, err := Detect(filename, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !format.CanRead() {
+		return nil, format, fmt.Errorf("%w: %s", ErrNotReadable, format.Name)
+	}
+
+	if format.DecodeScene != nil {
+		s, err := format.DecodeScene(data, opts)
+		if err != nil {
+			return nil, format, err
+		}
+		return s, format, nil
+	}
+
+	file, err := format.Decode(data, opts)
+	if err != nil {
+		return nil, format, err
+	}
+
+	if opts.Warn != nil {
+		for _, chunk := range file.UnknownChunks {
+			opts.Warn(fmt.Sprintf("unknown %s chunk is not converted to a scene", chunk.Tag))
+		}
+		if file.RawNodes != nil {
+			opts.Warn(fmt.Sprintf("%d nodes from the first light instance on are not converted to a scene", file.RawNodes.Count))
+		}
+	}
+
+	s, err := scene.FromSCW(file)
+	if err != nil {
+		return nil, format, err
+	}
+	return s, format, nil
+}
+
+// writableFormat returns the format registered as name if it can be written
+func writableFormat(name string) (*Format, error) {
+	format := Lookup(name)
+	if format == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+	}
+
+	if !format.CanWrite() {
+		return nil, fmt.Errorf("%w: %s", ErrNotWritable, format.Name)
+	}
+	return format, nil
+}
+
+// WriteFile encodes file in the format registered as name
+func WriteFile(filename, name string, file *scw.File) error {
+	format, err := writableFormat(name)
+	if err != nil {
+		return err
 	}
+	return format.Encode(filename, file)
+}
 
-	data, err := io.ReadAll(file)
+// WriteScene encodes s in the format registered as name, formats that are not built on the scene
+// get it as an scw File
+func WriteScene(filename, name string, s *scene.Scene) error {
+	format, err := writableFormat(name)
 	if err != nil {
+		return err
+	}
+
+	if format.EncodeScene != nil {
+		return format.EncodeScene(filename, s)
+	}
+
+	file, err := s.ToSCW()
+	if err != nil {
+		return err
+	}
+	return format.Encode(filename, file)
+}
+
+// isSCWJSON reports if data is a JSON object with an scw header, the Version key is matched
+// exactly as json.Unmarshal matches struct fields case-insensitively
+func isSCWJSON(data []byte) bool {
+	var object map[string]json.RawMessage
+	if json.Unmarshal(data, &object) != nil {
+		return false
+	}
+
+	var version uint16
+	return json.Unmarshal(object["Version"], &version) == nil
+}
+
+func decodeSCW(data []byte, opts Options) (*scw.File, error) {
+	return decodeSCWReader(bytes.NewReader(data), opts)
+}
+
+func decodeSCWReader(r io.Reader, opts Options) (*scw.File, error) {
+	file := scw.New(nil)
+	if opts.MinorVersion != nil {
+		file.MinorVersion = *opts.MinorVersion
+	}
+
+	if err := file.LoadFrom(r, scw.LoadOptions{VerifyCRC: opts.Strict, KeepRawSamples: opts.RawSamples}); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// CreateFile calls write with a buffered writer to a temporary file in the directory of filename,
+// which replaces filename once everything was written: filename is left as it was on errors so it
+// can be the file being converted, write, close and rename errors are returned
+//
+// the format packages write their outputs, sidecar files included, the same way
+func CreateFile(filename string, write func(w io.Writer) error) error {
+	return atomicfile.Create(filename, write)
+}
+
+func encodeSCW(filename string, file *scw.File) error {
+	return CreateFile(filename, file.EncodeTo)
+}
+
+func decodeSCWJSON(data []byte, _ Options) (*scw.File, error) {
+	file := scw.New(data)
+	if err := file.LoadJSON(); err != nil {
 		return nil, err
 	}
+	return file, nil
+}
+
+func encodeSCWJSON(filename string, file *scw.File) error {
+	return CreateFile(filename, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(file)
+	})
+}
 
-	if strings.HasSuffix(filename, ".json") {
-		filename = filename[:len(filename)-len(".json")]
+// importer adapts the Import functions of the format packages
+func importer(fn func(data []byte, dir string) (*scw.File, error)) func(data []byte, opts Options) (*scw.File, error) {
+	return func(data []byte, opts Options) (*scw.File, error) {
+		return fn(data, opts.Dir)
 	}
+}
+
+func decodeOBJ(data []byte, opts Options) (*scene.Scene, error) {
+	return obj.Import(data, opts.Dir, opts.Warn)
+}
 
-	for k, v := range formats {
-		if strings.HasSuffix(filename, k) {
-			return v(data), nil
+func init() {
+	for _, format := range []Format{
+		{Name: "scw", Extensions: []string{".scw"}, Magic: scw.IsSCW, Decode: decodeSCW, Encode: encodeSCW, DecodeReader: decodeSCWReader},
+		{Name: "scw.json", Extensions: []string{".scw.json", ".json"}, Magic: isSCWJSON, Decode: decodeSCWJSON, Encode: encodeSCWJSON},
+		{Name: "glb", Extensions: []string{".glb"}, Magic: gltf.IsGLB, Decode: importer(gltf.Import), Encode: gltf.WriteGLBFile},
+		{Name: "gltf", Extensions: []string{".gltf"}, Magic: gltf.IsGLTF, Decode: importer(gltf.Import), Encode: gltf.WriteFile},
+		{Name: "dae", Extensions: []string{".dae"}, Magic: dae.IsCollada, Decode: importer(dae.Import), Encode: dae.WriteFile},
+		{Name: "obj", Extensions: []string{".obj"}, DecodeScene: decodeOBJ, EncodeScene: obj.WriteFile},
+	} {
+		if err := Register(format); err != nil {
+			panic(err)
 		}
 	}
-	return nil, nil
 }
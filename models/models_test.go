@@ -0,0 +1,362 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models/scene"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+func TestLoadFromFileStreamsSCW(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("scw", "testdata", "*.scw"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no scw file in testdata: %v", err)
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			streamed, format, err := LoadFromFile(path, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format.Name != "scw" {
+				t.Fatalf("detected %s, expected scw", format.Name)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := decodeSCW(data, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a, _ := json.Marshal(streamed)
+			b, _ := json.Marshal(decoded)
+			if string(a) != string(b) {
+				t.Fatalf("streamed file differs from the decoded one:\n%s\n%s", a, b)
+			}
+		})
+	}
+}
+
+func TestCreateFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "model.scw")
+
+	if err := os.WriteFile(filename, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("write failed")
+	err := CreateFile(filename, func(w io.Writer) error {
+		_, _ = w.Write([]byte("partial"))
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("got %v, expected the write error", err)
+	}
+
+	if data, _ := os.ReadFile(filename); string(data) != "old" {
+		t.Fatalf("a failed write changed the file to %q", data)
+	}
+
+	if err = CreateFile(filename, func(w io.Writer) error {
+		_, err := w.Write([]byte("new"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filename); string(data) != "new" {
+		t.Fatalf("file is %q, expected new", data)
+	}
+
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0600 {
+		t.Fatalf("file mode %v, expected the one of the replaced file", info.Mode())
+	}
+
+	// no temporary file is left behind
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("%d files in the directory, expected 1", len(entries))
+	}
+}
+
+func TestWriteFileReplaces(t *testing.T) {
+	model, _, err := LoadFromFile(filepath.Join("scw", "testdata", "static.scw"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files written next to the output
+	sidecars := map[string]string{"gltf": "model.bin", "obj": "model.mtl"}
+
+	for _, format := range Formats() {
+		if !format.CanWrite() {
+			continue
+		}
+
+		t.Run(format.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "model"+format.Extensions[0])
+
+			// every written file is renamed over the existing one instead of being rewritten in place
+			files := []string{filename}
+			if sidecar, ok := sidecars[format.Name]; ok {
+				files = append(files, filepath.Join(dir, sidecar))
+			}
+
+			old := make([]os.FileInfo, len(files))
+			for i, file := range files {
+				if err := os.WriteFile(file, []byte("old"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				if old[i], err = os.Stat(file); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := WriteFile(filename, format.Name, model); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, file := range files {
+				info, err := os.Stat(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if os.SameFile(info, old[i]) || info.Mode().Perm() != 0600 {
+					t.Fatalf("%s was written in place or lost its mode %v", filepath.Base(file), info.Mode())
+				}
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(files) {
+				t.Fatalf("%d files in the directory, expected %d", len(entries), len(files))
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, test := range []struct {
+		name, filename string
+		data           string
+		format         string // empty for ErrUnknownFormat
+	}{
+		{"scw magic", "model.bin", scw.Magic + "\x00\x00", "scw"},
+		{"scw JSON", "model.txt", `{"Version": 2, "FrameRate": 30}`, "scw.json"},
+		{"scw JSON version case", "model.txt", `{"version": 2}`, ""},
+		{"scw JSON version not a number", "model.txt", `{"Version": "2"}`, ""},
+		{"JSON array", "model.txt", `[{"Version": 2}]`, ""},
+		{"glTF", "model.txt", `{"asset": {"version": "2.0"}}`, "gltf"},
+		{"GLB magic", "model.txt", "glTF\x02\x00\x00\x00", "glb"},
+		{"COLLADA", "model.txt", `<?xml version="1.0"?><COLLADA version="1.4.1">`, "dae"},
+		{"magic before extension", "model.obj", scw.Magic, "scw"},
+		{"extension", "model.obj", "v 0 0 0", "obj"},
+		{"extension case", "MODEL.OBJ", "v 0 0 0", "obj"},
+		{"JSON by extension", "model.json", `{"version": 2}`, "scw.json"},
+		{"unknown", "model.txt", "v 0 0 0", ""},
+		{"empty", "", "", ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := Detect(test.filename, []byte(test.data))
+
+			if len(test.format) == 0 {
+				if !errors.Is(err, ErrUnknownFormat) {
+					t.Fatalf("got %v, expected ErrUnknownFormat", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format.Name != test.format {
+				t.Fatalf("detected %s, expected %s", format.Name, test.format)
+			}
+		})
+	}
+}
+
+func TestLoadFromFileUnknownFormat(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "model.txt")
+	if err := os.WriteFile(filename, []byte(`{"version": 2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := LoadFromFile(filename, Options{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("got %v, expected ErrUnknownFormat", err)
+	}
+}
+
+func TestByExtension(t *testing.T) {
+	for _, test := range []struct {
+		filename, format string // empty format for ErrUnknownFormat
+	}{
+		{"model.scw", "scw"},
+		{"model.scw.json", "scw.json"},
+		{"model.json", "scw.json"},
+		{"dir.glb/model.gltf", "gltf"},
+		{"MODEL.GLB", "glb"},
+		{"model.dae", "dae"},
+		{"model.obj", "obj"},
+		{"model.mtl", ""},
+		{"model", ""},
+		{"obj", ""},
+	} {
+		format, err := ByExtension(test.filename)
+
+		if len(test.format) == 0 {
+			if !errors.Is(err, ErrUnknownFormat) {
+				t.Errorf("%s: got %v, expected ErrUnknownFormat", test.filename, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", test.filename, err)
+		} else if format.Name != test.format {
+			t.Errorf("%s: got %s, expected %s", test.filename, format.Name, test.format)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	registered := formats
+	t.Cleanup(func() { formats = registered })
+
+	encode := func(string, *scw.File) error { return nil }
+
+	for _, test := range []struct {
+		name   string
+		format Format
+		ok     bool
+	}{
+		{"no name", Format{Extensions: []string{".test"}, Encode: encode}, false},
+		{"no decoder nor encoder", Format{Name: "test", Extensions: []string{".test"}}, false},
+		{"duplicate", Format{Name: "scw", Extensions: []string{".test"}, Encode: encode}, false},
+		{"write only", Format{Name: "test", Extensions: []string{".test", ".scw.test"}, Encode: encode}, true},
+	} {
+		err := Register(test.format)
+		if (err == nil) != test.ok {
+			t.Fatalf("%s: got %v", test.name, err)
+		}
+	}
+
+	format := Lookup("test")
+	if format == nil || format.CanRead() || !format.CanWrite() {
+		t.Fatalf("registered format %+v", format)
+	}
+
+	if found, err := ByExtension("model.scw.test"); err != nil || found.Name != "test" {
+		t.Fatalf("got %v %v, expected the registered format", found, err)
+	}
+
+	if Formats()[len(Formats())-1].Name != "test" {
+		t.Fatal("registered format is not listed last")
+	}
+
+	// read only and write only formats
+	filename := filepath.Join(t.TempDir(), "model.test")
+	if err := os.WriteFile(filename, []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := LoadFromFile(filename, Options{}); !errors.Is(err, ErrNotReadable) {
+		t.Fatalf("got %v, expected ErrNotReadable", err)
+	}
+	if err := WriteFile(filename, "unknown", nil); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("got %v, expected ErrUnknownFormat", err)
+	}
+}
+
+func TestSceneFormat(t *testing.T) {
+	registered := formats
+	t.Cleanup(func() { formats = registered })
+
+	mesh := &scene.Mesh{
+		Name:       "triangle",
+		Attributes: []scene.Attribute{{Name: "POSITION", Components: 3, Data: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}}},
+		Primitives: []scene.Primitive{{Indices: []uint32{0, 1, 2}}},
+	}
+	decoded := &scene.Scene{
+		Meshes: []*scene.Mesh{mesh},
+		Nodes:  []*scene.Node{{Name: "root", Transform: scene.IdentityTransform(), Meshes: []scene.MeshInstance{{Mesh: mesh}}}},
+	}
+
+	var encoded *scene.Scene
+	err := Register(Format{
+		Name:        "scene",
+		Extensions:  []string{".scene"},
+		DecodeScene: func([]byte, Options) (*scene.Scene, error) { return decoded, nil },
+		EncodeScene: func(_ string, s *scene.Scene) error { encoded = s; return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format := Lookup("scene")
+	if !format.CanRead() || !format.CanWrite() {
+		t.Fatal("Decode and Encode are not derived from the scene codecs")
+	}
+
+	filename := filepath.Join(t.TempDir(), "model.scene")
+	if err = os.WriteFile(filename, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// as an scw File
+	file, _, err := LoadFromFile(filename, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Geometries) != 1 || file.Geometries[0].Name != "triangle" || len(file.Nodes) != 1 {
+		t.Fatalf("loaded %d geometries and %d nodes, expected the triangle and its node", len(file.Geometries), len(file.Nodes))
+	}
+
+	if err = WriteFile(filename, "scene", file); err != nil {
+		t.Fatal(err)
+	}
+	if encoded == nil || len(encoded.Meshes) != 1 || encoded.Meshes[0].Name != "triangle" {
+		t.Fatalf("encoded %+v, expected the triangle", encoded)
+	}
+
+	// as a scene, without going through scw
+	s, _, err := LoadScene(filename, Options{})
+	if err != nil || s != decoded {
+		t.Fatalf("got %v %v, expected the decoded scene", s, err)
+	}
+
+	if err = WriteScene(filename, "scene", s); err != nil || encoded != s {
+		t.Fatalf("got %v, expected the scene to be encoded as is", err)
+	}
+}
+
+func TestLoadSceneWarnings(t *testing.T) {
+	var warnings []string
+	s, format, err := LoadScene(filepath.Join("scw", "testdata", "lights.scw"), Options{Warn: func(msg string) {
+		warnings = append(warnings, msg)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if format.Name != "scw" || len(s.Meshes) != 1 || len(s.Nodes) == 0 {
+		t.Fatalf("loaded %s with %d meshes and %d root nodes, expected an scw scene", format.Name, len(s.Meshes), len(s.Nodes))
+	}
+
+	// the 2 LIGH chunks and the raw nodes are dropped
+	if len(warnings) != 3 {
+		t.Fatalf("warnings %q, expected 3", warnings)
+	}
+}
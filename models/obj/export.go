@@ -0,0 +1,201 @@
+package obj
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/PeterHackz/conv3d/models/internal/atomicfile"
+	"github.com/PeterHackz/conv3d/models/scene"
+)
+
+// Wavefront OBJ (static geometry only, node transforms and skins are ignored)
+//
+// reference: https://paulbourke.net/dataformats/obj/ and https://paulbourke.net/dataformats/mtl/
+
+// Export writes every mesh of s as an OBJ group and its materials as an MTL library
+//
+// mtlName is the library file name referenced by the OBJ (mtllib)
+func Export(s *scene.Scene, mtlName string) ([]byte, []byte, error) {
+	var out bytes.Buffer
+
+	out.WriteString("# exported by conv3d\n")
+	if len(s.Materials) > 0 {
+		fmt.Fprintf(&out, "mtllib %s\n", mtlName)
+	}
+
+	// OBJ indices are global (and 1 based), so each mesh offsets its indices by the vertices written before
+	offset := 0
+
+	for _, mesh := range s.Meshes {
+		written, err := exportMesh(&out, s, mesh, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+		offset += written
+	}
+
+	var mtl bytes.Buffer
+	for _, mat := range s.Materials {
+		exportMaterial(&mtl, mat)
+	}
+
+	return out.Bytes(), mtl.Bytes(), nil
+}
+
+// WriteFile exports s as a .obj next to a .mtl with the same base name,
+// each of them replaces its file only once it was fully written
+func WriteFile(filename string, s *scene.Scene) error {
+	mtlName := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + ".mtl"
+
+	data, mtl, err := Export(s, mtlName)
+	if err != nil {
+		return err
+	}
+
+	if len(s.Materials) > 0 {
+		if err = atomicfile.WriteFile(filepath.Join(filepath.Dir(filename), mtlName), mtl); err != nil {
+			return err
+		}
+	}
+
+	return atomicfile.WriteFile(filename, data)
+}
+
+// materialBindings returns the symbol -> material bindings of the first node instancing mesh
+func materialBindings(s *scene.Scene, mesh *scene.Mesh) map[string]string {
+	var bindings map[string]string
+
+	s.Walk(func(node, _ *scene.Node) {
+		for _, instance := range node.Meshes {
+			if bindings == nil && instance.Mesh == mesh {
+				bindings = instance.Materials
+			}
+		}
+	})
+
+	return bindings
+}
+
+func writeAttribute(out *bytes.Buffer, prefix string, attr *scene.Attribute, components int) error {
+	if attr == nil {
+		return nil
+	}
+
+	if attr.Components < components {
+		return fmt.Errorf("attribute %s needs at least %d components", attr.Name, components)
+	}
+
+	for i := 0; i+attr.Components <= len(attr.Data); i += attr.Components {
+		out.WriteString(prefix)
+		for _, v := range attr.Data[i : i+components] {
+			fmt.Fprintf(out, " %g", v)
+		}
+		out.WriteByte('\n')
+	}
+
+	return nil
+}
+
+// exportMesh writes one mesh and returns how many vertices it wrote,
+// every attribute has a value per vertex so they all share the vertex index
+func exportMesh(out *bytes.Buffer, s *scene.Scene, mesh *scene.Mesh, offset int) (int, error) {
+	count := mesh.VerticesCount()
+	if count == 0 {
+		return 0, fmt.Errorf("mesh %s has no POSITION attribute", mesh.Name)
+	}
+
+	// OBJ has a single texture coordinates set
+	texcoord := mesh.Attribute("TEXCOORD", 0)
+	normal := mesh.Attribute("NORMAL", 0)
+
+	for _, attr := range []*scene.Attribute{texcoord, normal} {
+		if attr != nil && len(attr.Data) != count*attr.Components {
+			return 0, fmt.Errorf("mesh %s: %s does not have a value per vertex", mesh.Name, attr.Name)
+		}
+	}
+
+	fmt.Fprintf(out, "\ng %s\n", mesh.Name)
+
+	if err := writeAttribute(out, "v", mesh.Attribute("POSITION", 0), 3); err != nil {
+		return 0, fmt.Errorf("mesh %s: %w", mesh.Name, err)
+	}
+	if err := writeAttribute(out, "vt", texcoord, 2); err != nil {
+		return 0, fmt.Errorf("mesh %s: %w", mesh.Name, err)
+	}
+	if err := writeAttribute(out, "vn", normal, 3); err != nil {
+		return 0, fmt.Errorf("mesh %s: %w", mesh.Name, err)
+	}
+
+	bindings := materialBindings(s, mesh)
+
+	for _, prim := range mesh.Primitives {
+		if len(prim.Indices)%3 != 0 {
+			return 0, fmt.Errorf("mesh %s: primitive %s is not a triangle list", mesh.Name, prim.Material)
+		}
+
+		if target, ok := bindings[prim.Material]; ok {
+			fmt.Fprintf(out, "usemtl %s\n", target)
+		} else if len(prim.Material) != 0 {
+			fmt.Fprintf(out, "usemtl %s\n", prim.Material)
+		}
+
+		for t := 0; t < len(prim.Indices); t += 3 {
+			out.WriteString("f")
+			for _, idx := range prim.Indices[t : t+3] {
+				if int(idx) >= count {
+					return 0, fmt.Errorf("mesh %s: index %d is out of range", mesh.Name, idx)
+				}
+
+				v := offset + int(idx) + 1
+				switch {
+				case texcoord != nil && normal != nil:
+					fmt.Fprintf(out, " %d/%d/%d", v, v, v)
+				case texcoord != nil:
+					fmt.Fprintf(out, " %d/%d", v, v)
+				case normal != nil:
+					fmt.Fprintf(out, " %d//%d", v, v)
+				default:
+					fmt.Fprintf(out, " %d", v)
+				}
+			}
+			out.WriteByte('\n')
+		}
+	}
+
+	return count, nil
+}
+
+func writeColor(out *bytes.Buffer, statement, mapStatement string, color *scene.Color) {
+	if len(color.Texture) != 0 {
+		fmt.Fprintf(out, "%s %s\n", mapStatement, color.Texture)
+		return
+	}
+
+	c := color.Value
+	fmt.Fprintf(out, "%s %g %g %g\n", statement, c[0], c[1], c[2])
+}
+
+func exportMaterial(out *bytes.Buffer, mat *scene.Material) {
+	fmt.Fprintf(out, "newmtl %s\n", mat.Name)
+
+	writeColor(out, "Ka", "map_Ka", &mat.Ambient)
+	writeColor(out, "Kd", "map_Kd", &mat.Diffuse)
+	writeColor(out, "Ks", "map_Ks", &mat.Specular)
+	writeColor(out, "Ke", "map_Ke", &mat.Emission)
+
+	if mat.Opacity > 0 {
+		fmt.Fprintf(out, "d %g\n", mat.Opacity)
+	}
+
+	if len(mat.OpacityTexture) != 0 {
+		fmt.Fprintf(out, "map_d %s\n", mat.OpacityTexture)
+	}
+
+	if len(mat.NormalTexture) != 0 {
+		fmt.Fprintf(out, "norm %s\n", mat.NormalTexture)
+	}
+
+	out.WriteByte('\n')
+}
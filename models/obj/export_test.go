@@ -0,0 +1,141 @@
+package obj
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models/internal/modeltest"
+	"github.com/PeterHackz/conv3d/models/scene"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+func TestRoundTrip(t *testing.T) {
+	file := modeltest.LoadFixture(t, "static.scw")
+
+	s, err := scene.FromSCW(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "model.obj")
+	if err = WriteFile(filename, s); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := ReadFile(filename, func(msg string) { t.Error(msg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := imported.ToSCW()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// OBJ has no nodes nor cameras, only the geometries and materials are compared
+	modeltest.CompareGeometries(t, file, out, 1e-5)
+
+	if len(imported.Materials) != len(file.Materials) {
+		t.Fatalf("imported %d materials, expected %d", len(imported.Materials), len(file.Materials))
+	}
+
+	// the diffuse of the fixture is a texture, its ambient a color
+	for i, mat := range file.Materials {
+		out := imported.Materials[i]
+		if out.Name != mat.Name || out.Diffuse.Texture != mat.Variables.Diffuse.Texture2D ||
+			scw.RGBAFromFloats(out.Ambient.Value[:]) != mat.Variables.Ambient.Color {
+			t.Fatalf("material %s (diffuse %s, ambient %v) imported as %s (diffuse %s, ambient %v)",
+				mat.Name, mat.Variables.Diffuse.Texture2D, mat.Variables.Ambient.Color,
+				out.Name, out.Diffuse.Texture, out.Ambient.Value)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	a := &scene.Mesh{
+		Name: "a",
+		Attributes: []scene.Attribute{
+			{Name: "POSITION", Components: 3, Data: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}},
+			{Name: "TEXCOORD", Components: 2, Data: []float32{0.5, 0.25, 0.5, 0.25, 0.5, 0.25}},
+		},
+		Primitives: []scene.Primitive{{Material: "sym", Indices: []uint32{0, 1, 2}}},
+	}
+
+	b := &scene.Mesh{
+		Name: "b",
+		Attributes: []scene.Attribute{
+			{Name: "POSITION", Components: 3, Data: []float32{0, 0, 1, 1, 0, 1, 0, 1, 1}},
+			{Name: "TEXCOORD", Components: 2, Data: []float32{1, 1, 1, 1, 1, 1}},
+			{Name: "NORMAL", Components: 3, Data: []float32{0, 0, 1, 0, 0, 1, 0, 0, 1}},
+		},
+		Primitives: []scene.Primitive{{Material: "blue", Indices: []uint32{0, 1, 2}}},
+	}
+
+	s := &scene.Scene{
+		Meshes: []*scene.Mesh{a, b},
+		Materials: []*scene.Material{
+			{Name: "red", Diffuse: scene.Color{Texture: "red.png"}, Opacity: 0.5},
+			{Name: "blue", Diffuse: scene.Color{Value: [4]float32{0, 0, 1, 1}}},
+		},
+		Nodes: []*scene.Node{{
+			Name:      "node",
+			Transform: scene.IdentityTransform(),
+			Meshes:    []scene.MeshInstance{{Mesh: a, Materials: map[string]string{"sym": "red"}}},
+		}},
+	}
+
+	data, mtl, err := Export(s, "model.mtl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// indices of the second mesh start after the vertices of the first one, the symbol of a is bound
+	// to red by the node while b has no instance and keeps its own name
+	expected := `# exported by conv3d
+mtllib model.mtl
+
+g a
+v 0 0 0
+v 1 0 0
+v 0 1 0
+vt 0.5 0.25
+vt 0.5 0.25
+vt 0.5 0.25
+usemtl red
+f 1/1 2/2 3/3
+
+g b
+v 0 0 1
+v 1 0 1
+v 0 1 1
+vt 1 1
+vt 1 1
+vt 1 1
+vn 0 0 1
+vn 0 0 1
+vn 0 0 1
+usemtl blue
+f 4/4/4 5/5/5 6/6/6
+`
+	if string(data) != expected {
+		t.Errorf("obj =\n%s\nexpected\n%s", data, expected)
+	}
+
+	expectedMTL := `newmtl red
+Ka 0 0 0
+map_Kd red.png
+Ks 0 0 0
+Ke 0 0 0
+d 0.5
+
+newmtl blue
+Ka 0 0 0
+Kd 0 0 1
+Ks 0 0 0
+Ke 0 0 0
+
+`
+	if string(mtl) != expectedMTL {
+		t.Errorf("mtl =\n%s\nexpected\n%s", mtl, expectedMTL)
+	}
+}
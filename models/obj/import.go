@@ -0,0 +1,431 @@
+package obj
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PeterHackz/conv3d/models/scene"
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+// rootNodeName is the node instancing every imported geometry
+const rootNodeName = "root"
+
+// corner is one face vertex, 0 means the attribute is not set (OBJ indices are 1 based)
+type corner struct {
+	position, texcoord, normal int
+}
+
+type group struct {
+	name  string
+	faces map[string][]corner // material -> triangles corners
+	order []string            // materials in the order they appeared
+}
+
+type parser struct {
+	positions, texcoords, normals [][]float64
+
+	groups  []*group
+	current *group
+	mtl     string
+
+	libraries []string
+}
+
+// ReadFile imports a .obj file, material libraries are resolved relatively to it
+func ReadFile(filename string, warn func(msg string)) (*scene.Scene, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Import(data, filepath.Dir(filename), warn)
+}
+
+// Import builds a scene from an OBJ file
+//
+// each object/group becomes a Mesh with a Primitive per usemtl, and a single root node
+// instances every mesh with its usemtl names bound to the materials of the same name
+//
+// warn is called for what is imported with defaults (missing material libraries), it can be nil
+func Import(data []byte, dir string, warn func(msg string)) (*scene.Scene, error) {
+	p := new(parser)
+	if err := p.parse(data); err != nil {
+		return nil, err
+	}
+
+	s := new(scene.Scene)
+
+	materials := make(map[string]*scene.Material)
+	var order []string
+
+	for _, library := range p.libraries {
+		mtl, err := os.ReadFile(filepath.Join(dir, library))
+		if errors.Is(err, fs.ErrNotExist) {
+			// libraries are often not shipped with the OBJ file, their materials get default ones
+			if warn != nil {
+				warn(fmt.Sprintf("obj: material library %s not found, default materials are used", library))
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if order, err = parseMTL(mtl, materials, order); err != nil {
+			return nil, fmt.Errorf("%s: %w", library, err)
+		}
+	}
+
+	root := &scene.Node{Name: rootNodeName, Transform: scene.IdentityTransform()}
+
+	used := make(map[string]bool)
+
+	for _, g := range p.groups {
+		if len(g.order) == 0 {
+			continue
+		}
+
+		mesh := p.buildMesh(g, used)
+		s.Meshes = append(s.Meshes, mesh)
+
+		instance := scene.MeshInstance{Mesh: mesh, Materials: make(map[string]string)}
+
+		for _, mtl := range g.order {
+			if len(mtl) == 0 {
+				continue
+			}
+
+			// materials used without being defined in a library get a plain white one
+			if _, ok := materials[mtl]; !ok {
+				materials[mtl] = newMaterial(mtl)
+				order = append(order, mtl)
+			}
+
+			instance.Materials[mtl] = mtl
+		}
+
+		root.Meshes = append(root.Meshes, instance)
+	}
+
+	for _, name := range order {
+		s.Materials = append(s.Materials, materials[name])
+	}
+
+	s.Nodes = []*scene.Node{root}
+
+	return s, nil
+}
+
+func parseFloats(fields []string, count int) ([]float64, error) {
+	if len(fields) < count {
+		return nil, fmt.Errorf("expected %d values, got %d", count, len(fields))
+	}
+
+	values := make([]float64, count)
+	for i := range values {
+		var err error
+		if values[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// resolveIndex converts a (possibly negative, relative) OBJ index to a 1 based absolute one
+func resolveIndex(field string, count int) (int, error) {
+	if len(field) == 0 {
+		return 0, nil
+	}
+
+	idx, err := strconv.Atoi(field)
+	if err != nil {
+		return 0, err
+	}
+
+	if idx < 0 {
+		idx += count + 1
+	}
+
+	if idx <= 0 || idx > count {
+		return 0, fmt.Errorf("index %s is out of range", field)
+	}
+
+	return idx, nil
+}
+
+func (p *parser) group(name string) {
+	// a group without faces is only a rename
+	if p.current != nil && len(p.current.order) == 0 {
+		p.current.name = name
+		return
+	}
+
+	p.current = &group{
+		name:  name,
+		faces: make(map[string][]corner),
+	}
+	p.groups = append(p.groups, p.current)
+}
+
+func (p *parser) face(fields []string) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("face has less than 3 vertices")
+	}
+
+	corners := make([]corner, len(fields))
+	for i, field := range fields {
+		parts := strings.Split(field, "/")
+
+		var err error
+		if corners[i].position, err = resolveIndex(parts[0], len(p.positions)); err != nil {
+			return err
+		}
+		if corners[i].position == 0 {
+			return fmt.Errorf("face vertex %s has no position", field)
+		}
+		if len(parts) > 1 {
+			if corners[i].texcoord, err = resolveIndex(parts[1], len(p.texcoords)); err != nil {
+				return err
+			}
+		}
+		if len(parts) > 2 {
+			if corners[i].normal, err = resolveIndex(parts[2], len(p.normals)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.current == nil {
+		p.group("default")
+	}
+
+	if _, ok := p.current.faces[p.mtl]; !ok {
+		p.current.order = append(p.current.order, p.mtl)
+	}
+
+	// polygons are triangulated as fans
+	for i := 1; i+1 < len(corners); i++ {
+		p.current.faces[p.mtl] = append(p.current.faces[p.mtl], corners[0], corners[i], corners[i+1])
+	}
+
+	return nil
+}
+
+func (p *parser) parse(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, math.MaxInt32)
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var err error
+
+		switch fields[0] {
+		case "v":
+			var values []float64
+			if values, err = parseFloats(fields[1:], 3); err == nil {
+				p.positions = append(p.positions, values)
+			}
+		case "vt":
+			var values []float64
+			if values, err = parseFloats(fields[1:], 2); err == nil {
+				p.texcoords = append(p.texcoords, values)
+			}
+		case "vn":
+			var values []float64
+			if values, err = parseFloats(fields[1:], 3); err == nil {
+				p.normals = append(p.normals, values)
+			}
+		case "f":
+			err = p.face(fields[1:])
+		case "o", "g":
+			p.group(strings.Join(fields[1:], " "))
+		case "usemtl":
+			p.mtl = strings.Join(fields[1:], " ")
+		case "mtllib":
+			p.libraries = append(p.libraries, fields[1:]...)
+		}
+		// other statements (s, l, p, curves...) have no scene equivalent
+
+		if err != nil {
+			return fmt.Errorf("obj line %d: %w", line, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// buildMesh turns the faces of a group into a mesh, every unique combination of position,
+// texture coordinate and normal indices becomes a vertex
+func (p *parser) buildMesh(g *group, used map[string]bool) *scene.Mesh {
+	mesh := &scene.Mesh{Name: scw.UniqueName(g.name, "default", used)}
+
+	hasTexcoords, hasNormals := false, false
+	for _, corners := range g.faces {
+		for _, c := range corners {
+			hasTexcoords = hasTexcoords || c.texcoord != 0
+			hasNormals = hasNormals || c.normal != 0
+		}
+	}
+
+	position := scene.Attribute{Name: "POSITION", Components: 3}
+	normal := scene.Attribute{Name: "NORMAL", Components: 3}
+	texcoord := scene.Attribute{Name: "TEXCOORD", Components: 2}
+
+	// faces without an attribute in a group where others have it get zeros
+	add := func(attr *scene.Attribute, idx int, values [][]float64) {
+		if idx == 0 {
+			attr.Data = append(attr.Data, make([]float32, attr.Components)...)
+			return
+		}
+		for _, v := range values[idx-1][:attr.Components] {
+			attr.Data = append(attr.Data, float32(v))
+		}
+	}
+
+	vertices := make(map[corner]uint32)
+
+	for _, mtl := range g.order {
+		corners := g.faces[mtl]
+		primitive := scene.Primitive{Material: mtl, Indices: make([]uint32, len(corners))}
+
+		for i, c := range corners {
+			if idx, ok := vertices[c]; ok {
+				primitive.Indices[i] = idx
+				continue
+			}
+
+			idx := uint32(len(vertices))
+			vertices[c] = idx
+			primitive.Indices[i] = idx
+
+			add(&position, c.position, p.positions)
+			if hasNormals {
+				add(&normal, c.normal, p.normals)
+			}
+			if hasTexcoords {
+				add(&texcoord, c.texcoord, p.texcoords)
+			}
+		}
+
+		mesh.Primitives = append(mesh.Primitives, primitive)
+	}
+
+	mesh.Attributes = append(mesh.Attributes, position)
+	if hasNormals {
+		mesh.Attributes = append(mesh.Attributes, normal)
+	}
+	if hasTexcoords {
+		mesh.Attributes = append(mesh.Attributes, texcoord)
+	}
+
+	return mesh
+}
+
+func newMaterial(name string) *scene.Material {
+	return &scene.Material{
+		Name:     name,
+		Ambient:  scene.Color{Value: [4]float32{0, 0, 0, 1}},
+		Diffuse:  scene.Color{Value: [4]float32{1, 1, 1, 1}},
+		Specular: scene.Color{Value: [4]float32{0, 0, 0, 1}},
+		Emission: scene.Color{Value: [4]float32{0, 0, 0, 1}},
+		Opacity:  1,
+	}
+}
+
+// parseColor sets the color of c, keeping its alpha
+func parseColor(fields []string, c *scene.Color) error {
+	values, err := parseFloats(fields, 3)
+	if err != nil {
+		return err
+	}
+
+	for i, v := range values {
+		c.Value[i] = float32(v)
+	}
+	return nil
+}
+
+// parseMTL adds the materials of an MTL library, order keeps the materials in their definition order
+func parseMTL(data []byte, materials map[string]*scene.Material, order []string) ([]string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	var mat *scene.Material
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if fields[0] == "newmtl" {
+			name := strings.Join(fields[1:], " ")
+			if _, ok := materials[name]; !ok {
+				order = append(order, name)
+			}
+			mat = newMaterial(name)
+			materials[name] = mat
+			continue
+		}
+
+		if mat == nil {
+			continue
+		}
+
+		var (
+			err   error
+			value string
+		)
+
+		// texture statements may have options before the file name, which is always last
+		if len(fields) > 1 {
+			value = fields[len(fields)-1]
+		}
+
+		switch fields[0] {
+		case "Ka":
+			err = parseColor(fields[1:], &mat.Ambient)
+		case "Kd":
+			err = parseColor(fields[1:], &mat.Diffuse)
+		case "Ks":
+			err = parseColor(fields[1:], &mat.Specular)
+		case "Ke":
+			err = parseColor(fields[1:], &mat.Emission)
+		case "d", "Tr":
+			var values []float64
+			if values, err = parseFloats(fields[1:], 1); err == nil {
+				mat.Opacity = float32(values[0])
+				if fields[0] == "Tr" {
+					mat.Opacity = 1 - mat.Opacity
+				}
+			}
+		case "map_Ka":
+			mat.Ambient.Texture = value
+		case "map_Kd":
+			mat.Diffuse.Texture = value
+		case "map_Ks":
+			mat.Specular.Texture = value
+		case "map_Ke":
+			mat.Emission.Texture = value
+		case "map_d":
+			mat.OpacityTexture = value
+		case "norm", "map_Bump", "map_bump", "bump":
+			mat.NormalTexture = value
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("mtl line %d: %w", line, err)
+		}
+	}
+
+	return order, scanner.Err()
+}
@@ -0,0 +1,67 @@
+package obj
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models/scene"
+)
+
+const triangle = `mtllib %s
+v 0 0 0
+v 1 0 0
+v 0 1 0
+usemtl red
+f 1 2 3
+`
+
+func importTriangle(t *testing.T, library string, mtl []byte) (*scene.Scene, []string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if mtl != nil {
+		if err := os.WriteFile(filepath.Join(dir, library), mtl, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var warnings []string
+	s, err := Import([]byte(fmt.Sprintf(triangle, library)), dir, func(msg string) {
+		warnings = append(warnings, msg)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Meshes) != 1 || len(s.Materials) != 1 || s.Materials[0].Name != "red" {
+		t.Fatalf("expected 1 mesh and the red material, got %d meshes and %d materials", len(s.Meshes), len(s.Materials))
+	}
+	return s, warnings
+}
+
+func TestImportLibrary(t *testing.T) {
+	s, warnings := importTriangle(t, "red.mtl", []byte("newmtl red\nKd 1 0 0\n"))
+
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings %q", warnings)
+	}
+
+	if color := s.Materials[0].Diffuse.Value; color != [4]float32{1, 0, 0, 1} {
+		t.Fatalf("diffuse color %v, expected red", color)
+	}
+}
+
+func TestImportMissingLibrary(t *testing.T) {
+	s, warnings := importTriangle(t, "missing.mtl", nil)
+
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "missing.mtl") {
+		t.Fatalf("warnings %q, expected one for missing.mtl", warnings)
+	}
+
+	if color := s.Materials[0].Diffuse.Value; color != [4]float32{1, 1, 1, 1} {
+		t.Fatalf("diffuse color %v, expected the default white", color)
+	}
+}
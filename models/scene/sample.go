@@ -0,0 +1,99 @@
+package scene
+
+import (
+	"math"
+	"sort"
+)
+
+func (c *Channel) components() int {
+	if c.Path == PathRotation {
+		return 4
+	}
+	return 3
+}
+
+func (c *Channel) value(k int) []float32 {
+	n := c.components()
+	return c.Values[k*n : (k+1)*n]
+}
+
+// Sample evaluates the channel at time, rotations are interpolated along the shortest path
+func (c *Channel) Sample(time float32, out []float32) {
+	last := len(c.Times) - 1
+	if last < 0 {
+		return
+	}
+
+	if time <= c.Times[0] {
+		copy(out, c.value(0))
+		return
+	}
+
+	if time >= c.Times[last] {
+		copy(out, c.value(last))
+		return
+	}
+
+	k := sort.Search(len(c.Times), func(i int) bool { return c.Times[i] >= time })
+	if c.Times[k] == time {
+		copy(out, c.value(k))
+		return
+	}
+	k-- // times[k] < time < times[k+1]
+
+	if c.Interpolation == InterpolationStep {
+		copy(out, c.value(k))
+		return
+	}
+
+	t := (time - c.Times[k]) / (c.Times[k+1] - c.Times[k])
+	if c.Path == PathRotation {
+		slerp(c.value(k), c.value(k+1), t, out)
+		return
+	}
+
+	a, b := c.value(k), c.value(k+1)
+	for i := range out {
+		out[i] = a[i] + (b[i]-a[i])*t
+	}
+}
+
+// slerp interpolates two (x, y, z, w) quaternions
+func slerp(a, b []float32, t float32, out []float32) {
+	dot := float64(a[0]*b[0] + a[1]*b[1] + a[2]*b[2] + a[3]*b[3])
+
+	sign := 1.0
+	if dot < 0 {
+		dot, sign = -dot, -1
+	}
+
+	wa, wb := 1-float64(t), float64(t)*sign
+	// close quaternions are linearly interpolated to avoid dividing by ~0
+	if dot < 0.9995 {
+		theta := math.Acos(dot)
+		sin := math.Sin(theta)
+		wa = math.Sin((1-float64(t))*theta) / sin
+		wb = math.Sin(float64(t)*theta) / sin * sign
+	}
+
+	length := 0.0
+	for i := range 4 {
+		v := float64(a[i])*wa + float64(b[i])*wb
+		out[i] = float32(v)
+		length += v * v
+	}
+
+	if length = math.Sqrt(length); length > 0 {
+		for i := range 4 {
+			out[i] = float32(float64(out[i]) / length)
+		}
+	}
+}
+
+func xfov(yfov, aspectRatio float32) float32 {
+	return float32(2 * math.Atan(math.Tan(float64(yfov)/2)*float64(aspectRatio)))
+}
+
+func yfov(xfov, aspectRatio float32) float32 {
+	return float32(2 * math.Atan(math.Tan(float64(xfov)/2)/float64(aspectRatio)))
+}
@@ -0,0 +1,191 @@
+package scene
+
+// Scene is a format neutral model, importers and exporters can work on it instead of
+// scw quirks (multi-input index arrays, quantized sources, frame flags...)
+//
+// rotations are (x, y, z, w) quaternions, angles are in radians and times in seconds
+//
+// there are no lights as the layout of scw lights is not known (see scw.ErrLightInstance)
+type Scene struct {
+	Materials  []*Material
+	Meshes     []*Mesh
+	Cameras    []*Camera
+	Nodes      []*Node // root nodes
+	Animations []*Animation
+
+	// FrameRate is the rate animations are sampled at when converted to key frames, 0 for the default
+	FrameRate float32
+}
+
+// Matrix is a row major 4x4 matrix, the translation is in the last column
+type Matrix [16]float32
+
+func Identity() Matrix {
+	return Matrix{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+}
+
+// Color is a linear RGBA color or a texture path if Texture is set
+type Color struct {
+	Value   [4]float32
+	Texture string
+}
+
+type Material struct {
+	Name string
+
+	Ambient, Diffuse, Specular, Emission Color
+
+	NormalTexture  string
+	Opacity        float32
+	OpacityTexture string
+}
+
+// Attribute is a stream of per vertex values (POSITION, NORMAL, TEXCOORD, COLOR...)
+type Attribute struct {
+	Name       string
+	Set        int // TEXCOORD and COLOR sets
+	Components int
+	Data       []float32
+}
+
+// Primitive is a triangle list, Material is a symbol bound by the mesh instances
+type Primitive struct {
+	Material string
+	Indices  []uint32
+}
+
+// Mesh is an indexed triangle mesh, every attribute has one value per vertex
+type Mesh struct {
+	Name       string
+	Attributes []Attribute
+	Primitives []Primitive
+	Skin       *Skin
+}
+
+// Attribute returns the attribute named name in set, nil if the mesh has none
+func (m *Mesh) Attribute(name string, set int) *Attribute {
+	for i := range m.Attributes {
+		if m.Attributes[i].Name == name && m.Attributes[i].Set == set {
+			return &m.Attributes[i]
+		}
+	}
+	return nil
+}
+
+// VerticesCount returns the number of vertices of the mesh (the length of its POSITION attribute)
+func (m *Mesh) VerticesCount() int {
+	position := m.Attribute("POSITION", 0)
+	if position == nil || position.Components == 0 {
+		return 0
+	}
+	return len(position.Data) / position.Components
+}
+
+// Skin binds a mesh to joint nodes, Weights has one entry per vertex
+type Skin struct {
+	BindMatrix          Matrix
+	Joints              []string // node names
+	InverseBindMatrices []Matrix
+	Weights             []VertexWeights
+}
+
+// VertexWeights are up to 4 joint influences, unused ones have a weight of 0
+type VertexWeights struct {
+	Joints  [4]int
+	Weights [4]float32
+}
+
+type Camera struct {
+	Name        string
+	YFov        float32
+	AspectRatio float32
+	ZNear, ZFar float32
+}
+
+// XFov returns the horizontal field of view
+func (c *Camera) XFov() float32 {
+	return xfov(c.YFov, c.AspectRatio)
+}
+
+// Transform is a local transformation (translation * rotation * scale)
+type Transform struct {
+	Translation [3]float32
+	Rotation    [4]float32
+	Scale       [3]float32
+}
+
+func IdentityTransform() Transform {
+	return Transform{Rotation: [4]float32{0, 0, 0, 1}, Scale: [3]float32{1, 1, 1}}
+}
+
+// MeshInstance places a mesh, Materials binds the primitive symbols to material names
+type MeshInstance struct {
+	Mesh      *Mesh
+	Materials map[string]string
+}
+
+// CameraInstance places a camera, Target is the name of the node it looks at (if any)
+type CameraInstance struct {
+	Camera *Camera
+	Target string
+}
+
+type Node struct {
+	Name      string
+	Transform Transform
+	Meshes    []MeshInstance
+	Cameras   []CameraInstance
+	Children  []*Node
+}
+
+// Walk calls fn for every node of the scene, parents before their children
+func (s *Scene) Walk(fn func(node, parent *Node)) {
+	var walk func(nodes []*Node, parent *Node)
+	walk = func(nodes []*Node, parent *Node) {
+		for _, node := range nodes {
+			fn(node, parent)
+			walk(node.Children, node)
+		}
+	}
+	walk(s.Nodes, nil)
+}
+
+type Path string
+
+const (
+	PathTranslation Path = "translation"
+	PathRotation    Path = "rotation"
+	PathScale       Path = "scale"
+)
+
+type Interpolation string
+
+const (
+	InterpolationLinear Interpolation = "LINEAR"
+	InterpolationStep   Interpolation = "STEP"
+)
+
+// Channel animates one property of a node, Values has 3 (4 for rotations) components per time
+type Channel struct {
+	Node          string
+	Path          Path
+	Interpolation Interpolation
+	Times         []float32
+	Values        []float32
+}
+
+type Animation struct {
+	Name     string
+	Channels []Channel
+}
+
+// Duration returns the time of the last key of the animation
+func (a *Animation) Duration() float32 {
+	var duration float32
+	for _, channel := range a.Channels {
+		if len(channel.Times) != 0 {
+			duration = max(duration, channel.Times[len(channel.Times)-1])
+		}
+	}
+	return duration
+}
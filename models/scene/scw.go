@@ -0,0 +1,624 @@
+package scene
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/PeterHackz/conv3d/models/scw"
+)
+
+func matrixFromSCW(m *scw.Matrix4x4) Matrix {
+	var out Matrix
+	for r := range 4 {
+		copy(out[r*4:], m[r][:])
+	}
+	return out
+}
+
+func (m *Matrix) scw() scw.Matrix4x4 {
+	var out scw.Matrix4x4
+	for r := range 4 {
+		copy(out[r][:], m[r*4:(r+1)*4])
+	}
+	return out
+}
+
+func colorFromSCW(variable *scw.Variable) Color {
+	if variable.UseText2D {
+		return Color{Texture: variable.Texture2D}
+	}
+
+	return Color{Value: variable.Color.Floats()}
+}
+
+func (c *Color) scw() scw.Variable {
+	if len(c.Texture) != 0 {
+		return scw.Variable{UseText2D: true, Texture2D: c.Texture}
+	}
+
+	return scw.Variable{Color: scw.RGBAFromFloats(c.Value[:])}
+}
+
+func transformFromSCW(frame *scw.KeyFrame) Transform {
+	return Transform{
+		Translation: [3]float32{frame.Translation.X, frame.Translation.Y, frame.Translation.Z},
+		Rotation:    [4]float32{frame.Rotation.X, frame.Rotation.Y, frame.Rotation.Z, frame.Rotation.W},
+		Scale:       [3]float32{frame.Scale.X, frame.Scale.Y, frame.Scale.Z},
+	}
+}
+
+func (t *Transform) keyFrame(id uint16) scw.KeyFrame {
+	return scw.KeyFrame{
+		ID: id,
+		Rotation: scw.Quaternion{
+			Vector3: scw.Vector3{X: t.Rotation[0], Y: t.Rotation[1], Z: t.Rotation[2]},
+			W:       t.Rotation[3],
+		},
+		Translation: scw.Vector3{X: t.Translation[0], Y: t.Translation[1], Z: t.Translation[2]},
+		Scale:       scw.Vector3{X: t.Scale[0], Y: t.Scale[1], Z: t.Scale[2]},
+	}
+}
+
+// FromSCW converts a loaded scw File
+//
+// scw index arrays index every input separately, so every unique combination of input indices
+// becomes a vertex, key frames become a single animation
+//
+// unknown chunks and raw nodes (see scw.Scene.RawNodes) are not converted
+func FromSCW(file *scw.File) (*Scene, error) {
+	s := &Scene{FrameRate: float32(file.FrameRate)}
+
+	for _, mat := range file.Materials {
+		vars := &mat.Variables
+		s.Materials = append(s.Materials, &Material{
+			Name:           mat.Name,
+			Ambient:        colorFromSCW(&vars.Ambient),
+			Diffuse:        colorFromSCW(&vars.Diffuse),
+			Specular:       colorFromSCW(&vars.Specular),
+			Emission:       colorFromSCW(&vars.Emission),
+			NormalTexture:  vars.NormalTex2D,
+			Opacity:        vars.Opacity,
+			OpacityTexture: vars.OpacityTex2D,
+		})
+	}
+
+	cameras := make(map[string]*Camera)
+	for _, cam := range file.Cameras {
+		// scw cameras mirror COLLADA ones which use degrees and may only have the horizontal fov
+		fov := cam.Yfov * math.Pi / 180
+		if fov == 0 && cam.AspectRatio != 0 {
+			fov = yfov(cam.Xfov*math.Pi/180, cam.AspectRatio)
+		}
+
+		camera := &Camera{
+			Name:        cam.Name,
+			YFov:        fov,
+			AspectRatio: cam.AspectRatio,
+			ZNear:       cam.ZNear,
+			ZFar:        cam.ZFar,
+		}
+		cameras[cam.Name] = camera
+		s.Cameras = append(s.Cameras, camera)
+	}
+
+	meshes := make(map[string]*Mesh)
+	for _, geom := range file.Geometries {
+		mesh, err := meshFromSCW(geom)
+		if err != nil {
+			return nil, err
+		}
+		meshes[geom.Name] = mesh
+		s.Meshes = append(s.Meshes, mesh)
+	}
+
+	nodes := make(map[string]*Node)
+	for i := range file.Nodes {
+		n := &file.Nodes[i]
+
+		node := &Node{Name: n.Name, Transform: IdentityTransform()}
+		if len(n.Frames) != 0 {
+			node.Transform = transformFromSCW(&n.Frames[0])
+		}
+
+		for _, instance := range n.Instances {
+			switch instance.Type {
+			case "GEOM", "CONT":
+				mesh, ok := meshes[instance.Target]
+				if !ok {
+					return nil, fmt.Errorf("node %s references unknown geometry: %s", n.Name, instance.Target)
+				}
+
+				bindings := make(map[string]string)
+				for _, mat := range instance.Materials {
+					bindings[mat.Name] = mat.Target
+				}
+				node.Meshes = append(node.Meshes, MeshInstance{Mesh: mesh, Materials: bindings})
+			case "CAME":
+				camera, ok := cameras[instance.Target]
+				if !ok {
+					return nil, fmt.Errorf("node %s references unknown camera: %s", n.Name, instance.Target)
+				}
+				node.Cameras = append(node.Cameras, CameraInstance{Camera: camera, Target: instance.CameraTarget})
+			default:
+				return nil, fmt.Errorf("node %s: unsupported instance type: %s", n.Name, instance.Type)
+			}
+		}
+
+		nodes[n.Name] = node
+	}
+
+	// nodes with an unknown parent are kept as roots
+	for i := range file.Nodes {
+		n := &file.Nodes[i]
+		if parent, ok := nodes[n.ParentName]; ok && len(n.ParentName) != 0 && n.ParentName != n.Name {
+			parent.Children = append(parent.Children, nodes[n.Name])
+		} else {
+			s.Nodes = append(s.Nodes, nodes[n.Name])
+		}
+	}
+
+	if animation := animationFromSCW(file); animation != nil {
+		s.Animations = append(s.Animations, animation)
+	}
+
+	return s, nil
+}
+
+func meshFromSCW(geom *scw.Geometry) (*Mesh, error) {
+	mesh := &Mesh{Name: geom.Name}
+
+	var position *scw.SourceArray
+	for i := range geom.Vertices {
+		src := &geom.Vertices[i]
+		if src.Stride == 0 {
+			return nil, fmt.Errorf("geometry %s: source %s has a stride of 0", geom.Name, src.Name)
+		}
+
+		name := src.Name
+		if src.IsPosition() {
+			name = "POSITION"
+			position = src
+		}
+
+		mesh.Attributes = append(mesh.Attributes, Attribute{
+			Name:       name,
+			Set:        int(src.SourceIndex),
+			Components: int(src.Stride),
+		})
+	}
+
+	if position == nil {
+		return nil, fmt.Errorf("geometry %s has no POSITION source", geom.Name)
+	}
+
+	skinned := len(geom.Skins.Joints) > 0
+	if skinned {
+		mesh.Skin = &Skin{BindMatrix: Identity(), Joints: geom.Skins.Joints}
+		if geom.HasBindMatrix {
+			mesh.Skin.BindMatrix = matrixFromSCW(&geom.BindMatrix)
+		}
+		for i := range geom.Skins.InverseBindMatrices {
+			mesh.Skin.InverseBindMatrices = append(mesh.Skin.InverseBindMatrices, matrixFromSCW(&geom.Skins.InverseBindMatrices[i]))
+		}
+	}
+
+	vertices := make(map[string]uint32)
+
+	for _, prim := range geom.Materials {
+		inputs := int(prim.InputsCount)
+		corners := 3 * int(prim.TrianglesCount)
+
+		if len(prim.IndexBuffer) < corners*inputs {
+			return nil, fmt.Errorf("geometry %s: index array %s is too short", geom.Name, prim.Name)
+		}
+
+		primitive := Primitive{Material: prim.Name, Indices: make([]uint32, corners)}
+
+		for c := range corners {
+			tuple := prim.IndexBuffer[c*inputs : (c+1)*inputs]
+
+			key := make([]byte, 4*inputs)
+			for j, idx := range tuple {
+				binary.LittleEndian.PutUint32(key[j*4:], idx)
+			}
+
+			if idx, ok := vertices[string(key)]; ok {
+				primitive.Indices[c] = idx
+				continue
+			}
+
+			idx := uint32(len(vertices))
+			vertices[string(key)] = idx
+			primitive.Indices[c] = idx
+
+			for a := range geom.Vertices {
+				src := &geom.Vertices[a]
+
+				offset := int(src.Index)
+				if offset >= inputs {
+					return nil, fmt.Errorf("geometry %s: source %s uses input %d but index array %s only has %d inputs", geom.Name, src.Name, offset, prim.Name, inputs)
+				}
+
+				stride := int(src.Stride)
+				start := int(tuple[offset]) * stride
+				if start+stride > len(src.Data) {
+					return nil, fmt.Errorf("geometry %s: index %d out of range for source %s", geom.Name, tuple[offset], src.Name)
+				}
+
+				for _, v := range src.Data[start : start+stride] {
+					mesh.Attributes[a].Data = append(mesh.Attributes[a].Data, float32(v))
+				}
+			}
+
+			if skinned {
+				// skin weights are stored per position
+				posIdx := int(tuple[position.Index])
+				if posIdx >= len(geom.SkinWeights) {
+					return nil, fmt.Errorf("geometry %s: no skin weight for position %d", geom.Name, posIdx)
+				}
+				mesh.Skin.Weights = append(mesh.Skin.Weights, weightsFromSCW(&geom.SkinWeights[posIdx]))
+			}
+		}
+
+		mesh.Primitives = append(mesh.Primitives, primitive)
+	}
+
+	return mesh, nil
+}
+
+func weightsFromSCW(weight *scw.Weight) VertexWeights {
+	var (
+		out VertexWeights
+		sum float32
+	)
+
+	for i, w := range weight.Weights {
+		out.Joints[i] = int(weight.Joints[i])
+		out.Weights[i] = float32(w)
+		sum += float32(w)
+	}
+
+	if sum == 0 {
+		return VertexWeights{Weights: [4]float32{1, 0, 0, 0}}
+	}
+
+	for i := range out.Weights {
+		out.Weights[i] /= sum
+	}
+	return out
+}
+
+// animationFromSCW converts the key frames inside the [FirstFrame, LastFrame] range,
+// files with both set to 0 have no range so every frame is kept
+func animationFromSCW(file *scw.File) *Animation {
+	rate := float32(file.FrameRate)
+	if rate == 0 {
+		rate = scw.DefaultFrameRate
+	}
+
+	ranged := file.FirstFrame != 0 || file.LastFrame != 0
+	animation := new(Animation)
+
+	for i := range file.Nodes {
+		node := &file.Nodes[i]
+
+		var frames []*scw.KeyFrame
+		for f := range node.Frames {
+			frame := &node.Frames[f]
+			if !ranged || frame.ID >= file.FirstFrame && frame.ID <= file.LastFrame {
+				frames = append(frames, frame)
+			}
+		}
+
+		if len(frames) < 2 {
+			continue
+		}
+
+		translation := Channel{Node: node.Name, Path: PathTranslation, Interpolation: InterpolationLinear}
+		rotation := Channel{Node: node.Name, Path: PathRotation, Interpolation: InterpolationLinear}
+		scale := Channel{Node: node.Name, Path: PathScale, Interpolation: InterpolationLinear}
+
+		for _, frame := range frames {
+			time := float32(int(frame.ID)-int(file.FirstFrame)) / rate
+			t := transformFromSCW(frame)
+
+			translation.Times = append(translation.Times, time)
+			translation.Values = append(translation.Values, t.Translation[:]...)
+			rotation.Times = append(rotation.Times, time)
+			rotation.Values = append(rotation.Values, t.Rotation[:]...)
+			scale.Times = append(scale.Times, time)
+			scale.Values = append(scale.Values, t.Scale[:]...)
+		}
+
+		animation.Channels = append(animation.Channels, translation, rotation, scale)
+	}
+
+	if len(animation.Channels) == 0 {
+		return nil
+	}
+	return animation
+}
+
+// ToSCW converts the scene to a version 2 scw File
+//
+// every attribute becomes a source at its own input, only the first animation is kept
+// since scw files hold a single timeline
+func (s *Scene) ToSCW() (*scw.File, error) {
+	frameRate := uint16(math.Round(float64(s.FrameRate)))
+	if frameRate == 0 {
+		frameRate = scw.DefaultFrameRate
+	}
+
+	file := &scw.File{}
+	file.Header = scw.Header{
+		Version:   2,
+		FrameRate: frameRate,
+	}
+
+	for _, mat := range s.Materials {
+		m := &scw.Material{SCWFile: file, Name: mat.Name}
+		vars := &m.Variables
+		vars.Ambient = mat.Ambient.scw()
+		vars.Diffuse = mat.Diffuse.scw()
+		vars.Specular = mat.Specular.scw()
+		vars.Emission = mat.Emission.scw()
+		vars.NormalTex2D = mat.NormalTexture
+		vars.Opacity = mat.Opacity
+		vars.OpacityTex2D = mat.OpacityTexture
+		file.Materials = append(file.Materials, m)
+	}
+
+	for _, cam := range s.Cameras {
+		file.Cameras = append(file.Cameras, &scw.Camera3D{
+			Name:        cam.Name,
+			Yfov:        cam.YFov * 180 / math.Pi,
+			Xfov:        cam.XFov() * 180 / math.Pi,
+			AspectRatio: cam.AspectRatio,
+			ZNear:       cam.ZNear,
+			ZFar:        cam.ZFar,
+		})
+	}
+
+	for _, mesh := range s.Meshes {
+		geom, err := mesh.scw(file)
+		if err != nil {
+			return nil, err
+		}
+		file.Geometries = append(file.Geometries, geom)
+	}
+
+	nodes := make(map[string]int)
+	var err error
+
+	s.Walk(func(node, parent *Node) {
+		if err != nil {
+			return
+		}
+
+		if _, ok := nodes[node.Name]; ok {
+			err = fmt.Errorf("node name %s is used more than once", node.Name)
+			return
+		}
+
+		n := scw.Node{
+			SCWFile: file,
+			Name:    node.Name,
+			Frames:  []scw.KeyFrame{node.Transform.keyFrame(0)},
+		}
+		if parent != nil {
+			n.ParentName = parent.Name
+		}
+
+		for _, instance := range node.Meshes {
+			out := scw.NodeInstance{Type: "GEOM", Target: instance.Mesh.Name}
+			if instance.Mesh.Skin != nil {
+				out.Type = "CONT"
+			}
+
+			// primitives order keeps the bindings stable
+			bound := make(map[string]bool)
+			for _, prim := range instance.Mesh.Primitives {
+				target, ok := instance.Materials[prim.Material]
+				if !ok || bound[prim.Material] {
+					continue
+				}
+				bound[prim.Material] = true
+				out.Materials = append(out.Materials, scw.InstanceMaterial{Name: prim.Material, Target: target})
+			}
+
+			n.Instances = append(n.Instances, out)
+		}
+
+		for _, instance := range node.Cameras {
+			n.Instances = append(n.Instances, scw.NodeInstance{
+				Type:         "CAME",
+				Target:       instance.Camera.Name,
+				CameraTarget: instance.Target,
+			})
+		}
+
+		nodes[node.Name] = len(file.Nodes)
+		file.Nodes = append(file.Nodes, n)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Animations) != 0 {
+		if err = s.Animations[0].sample(file, nodes); err != nil {
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+func (m *Mesh) scw(file *scw.File) (*scw.Geometry, error) {
+	geom := &scw.Geometry{SCWFile: file, Name: m.Name}
+
+	count := m.VerticesCount()
+	if count == 0 {
+		return nil, fmt.Errorf("mesh %s has no POSITION attribute", m.Name)
+	}
+
+	for a, attr := range m.Attributes {
+		if attr.Components == 0 || len(attr.Data) != count*attr.Components {
+			return nil, fmt.Errorf("mesh %s: %s does not have a value per vertex", m.Name, attr.Name)
+		}
+
+		data := make([]float64, len(attr.Data))
+		for i, v := range attr.Data {
+			data[i] = float64(v)
+		}
+
+		geom.Vertices = append(geom.Vertices, scw.SourceArray{
+			Name:        attr.Name,
+			Index:       byte(a),
+			SourceIndex: byte(attr.Set),
+			Stride:      byte(attr.Components),
+			Scale:       scw.QuantizationScale(data),
+			Data:        data,
+		})
+	}
+
+	indexBufferSize := byte(4)
+	if count <= math.MaxUint8+1 {
+		indexBufferSize = 1
+	} else if count <= math.MaxUint16+1 {
+		indexBufferSize = 2
+	}
+
+	inputs := len(m.Attributes)
+	for _, prim := range m.Primitives {
+		if len(prim.Indices)%3 != 0 {
+			return nil, fmt.Errorf("mesh %s: primitive %s is not a triangle list", m.Name, prim.Material)
+		}
+
+		// every source is its own input, they all share the vertex index
+		buffer := make([]uint32, 0, len(prim.Indices)*inputs)
+		for _, idx := range prim.Indices {
+			if int(idx) >= count {
+				return nil, fmt.Errorf("mesh %s: index %d is out of range", m.Name, idx)
+			}
+			for range inputs {
+				buffer = append(buffer, idx)
+			}
+		}
+
+		geom.Materials = append(geom.Materials, scw.IndexArray{
+			Name:            prim.Material,
+			IndexBufferSize: indexBufferSize,
+			IndexBuffer:     buffer,
+			TrianglesCount:  uint32(len(prim.Indices) / 3),
+			InputsCount:     byte(inputs),
+		})
+	}
+
+	if skin := m.Skin; skin != nil {
+		if len(skin.Weights) != count {
+			return nil, fmt.Errorf("mesh %s: skin weights do not match its vertices", m.Name)
+		}
+
+		if len(skin.InverseBindMatrices) != len(skin.Joints) {
+			return nil, fmt.Errorf("mesh %s: inverse bind matrices do not match its joints", m.Name)
+		}
+
+		geom.HasBindMatrix = true
+		geom.BindMatrix = skin.BindMatrix.scw()
+		geom.Skins.Joints = skin.Joints
+		for i := range skin.InverseBindMatrices {
+			geom.Skins.InverseBindMatrices = append(geom.Skins.InverseBindMatrices, skin.InverseBindMatrices[i].scw())
+		}
+
+		for _, vw := range skin.Weights {
+			var weight scw.Weight
+			var sum float32
+			for _, w := range vw.Weights {
+				sum += w
+			}
+
+			for i := range 4 {
+				if vw.Joints[i] < 0 || vw.Joints[i] > math.MaxUint8 || vw.Joints[i] >= len(skin.Joints) && vw.Weights[i] != 0 {
+					return nil, fmt.Errorf("mesh %s has an invalid joint index: %d", m.Name, vw.Joints[i])
+				}
+				weight.Joints[i] = byte(vw.Joints[i])
+				if sum > 0 {
+					weight.Weights[i] = uint16(math.Round(float64(vw.Weights[i] / sum * math.MaxUint16)))
+				}
+			}
+
+			geom.SkinWeights = append(geom.SkinWeights, weight)
+		}
+	}
+
+	return geom, nil
+}
+
+// sample evaluates the animation at every frame into the key frames of the animated nodes
+func (a *Animation) sample(file *scw.File, nodes map[string]int) error {
+	channels := make(map[string][]*Channel)
+	var order []string
+
+	for i := range a.Channels {
+		channel := &a.Channels[i]
+		if _, ok := nodes[channel.Node]; !ok {
+			return fmt.Errorf("animation %s targets unknown node: %s", a.Name, channel.Node)
+		}
+
+		if len(channel.Values) != len(channel.Times)*channel.components() {
+			return fmt.Errorf("animation %s: %s channel of %s does not match its times", a.Name, channel.Path, channel.Node)
+		}
+
+		if len(channel.Times) == 0 {
+			continue
+		}
+
+		if _, ok := channels[channel.Node]; !ok {
+			order = append(order, channel.Node)
+		}
+		channels[channel.Node] = append(channels[channel.Node], channel)
+	}
+
+	if len(order) == 0 {
+		return nil
+	}
+
+	rate := float32(file.FrameRate)
+	lastFrame := int(math.Round(float64(a.Duration() * rate)))
+	if lastFrame > math.MaxUint16 {
+		return fmt.Errorf("animation is too long: %d frames", lastFrame)
+	}
+
+	file.FirstFrame = 0
+	file.LastFrame = uint16(lastFrame)
+
+	for _, name := range order {
+		node := &file.Nodes[nodes[name]]
+		rest := transformFromSCW(&node.Frames[0])
+
+		frames := make([]scw.KeyFrame, lastFrame+1)
+		for f := range frames {
+			t := rest
+			time := float32(f) / rate
+
+			for _, channel := range channels[name] {
+				switch channel.Path {
+				case PathTranslation:
+					channel.Sample(time, t.Translation[:])
+				case PathRotation:
+					channel.Sample(time, t.Rotation[:])
+				case PathScale:
+					channel.Sample(time, t.Scale[:])
+				}
+			}
+
+			frames[f] = t.keyFrame(uint16(f))
+		}
+
+		node.Frames = frames
+	}
+
+	return nil
+}
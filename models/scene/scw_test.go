@@ -0,0 +1,110 @@
+package scene
+
+import (
+	"math"
+	"testing"
+
+	"github.com/PeterHackz/conv3d/models/internal/modeltest"
+)
+
+func TestSCWRoundTrip(t *testing.T) {
+	for _, name := range []string{"v0_minor0.scw", "v1.scw", "v2.scw", "static.scw", "animated.scw"} {
+		t.Run(name, func(t *testing.T) {
+			file := modeltest.LoadFixture(t, name)
+
+			s, err := FromSCW(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := s.ToSCW()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			modeltest.CompareGeometries(t, file, out, 1e-6)
+			modeltest.CompareScene(t, file, out)
+
+			// the animation is sampled at every frame from FirstFrame on, which becomes frame 0
+			for i, node := range file.Nodes {
+				if len(node.Frames) < 2 {
+					continue
+				}
+
+				for _, frame := range node.Frames {
+					id := int(frame.ID) - int(file.FirstFrame)
+					if id < 0 || id >= len(out.Nodes[i].Frames) {
+						continue
+					}
+
+					sampled := out.Nodes[i].Frames[id]
+					if !modeltest.SameRotation(sampled.Rotation, frame.Rotation) ||
+						math.Abs(float64(sampled.Translation.X-frame.Translation.X)) > 1e-5 {
+						t.Fatalf("node %s: frame %+v sampled as %+v", node.Name, frame, sampled)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestChannelSample(t *testing.T) {
+	// a quarter turn around Z
+	quarter := float32(math.Sqrt2 / 2)
+
+	tests := []struct {
+		name     string
+		channel  Channel
+		time     float32
+		expected []float32
+	}{
+		{
+			name:     "linear",
+			channel:  Channel{Path: PathTranslation, Interpolation: InterpolationLinear, Times: []float32{0, 1}, Values: []float32{0, 0, 0, 2, 4, 6}},
+			time:     0.5,
+			expected: []float32{1, 2, 3},
+		},
+		{
+			name:     "step",
+			channel:  Channel{Path: PathScale, Interpolation: InterpolationStep, Times: []float32{0, 1}, Values: []float32{1, 1, 1, 2, 2, 2}},
+			time:     0.9,
+			expected: []float32{1, 1, 1},
+		},
+		{
+			name:     "before the first key",
+			channel:  Channel{Path: PathTranslation, Interpolation: InterpolationLinear, Times: []float32{1, 2}, Values: []float32{1, 2, 3, 4, 5, 6}},
+			time:     0,
+			expected: []float32{1, 2, 3},
+		},
+		{
+			name:     "after the last key",
+			channel:  Channel{Path: PathTranslation, Interpolation: InterpolationLinear, Times: []float32{1, 2}, Values: []float32{1, 2, 3, 4, 5, 6}},
+			time:     3,
+			expected: []float32{4, 5, 6},
+		},
+		{
+			name:     "slerp",
+			channel:  Channel{Path: PathRotation, Interpolation: InterpolationLinear, Times: []float32{0, 1}, Values: []float32{0, 0, 0, 1, 0, 0, quarter, quarter}},
+			time:     0.5,
+			expected: []float32{0, 0, float32(math.Sin(math.Pi / 8)), float32(math.Cos(math.Pi / 8))},
+		},
+		{
+			name:     "slerp along the shortest path",
+			channel:  Channel{Path: PathRotation, Interpolation: InterpolationLinear, Times: []float32{0, 1}, Values: []float32{0, 0, 0, 1, 0, 0, -quarter, -quarter}},
+			time:     0.5,
+			expected: []float32{0, 0, float32(math.Sin(math.Pi / 8)), float32(math.Cos(math.Pi / 8))},
+		},
+	}
+
+	for _, test := range tests {
+		out := make([]float32, len(test.expected))
+		test.channel.Sample(test.time, out)
+
+		for i := range out {
+			if math.Abs(float64(out[i]-test.expected[i])) > 1e-6 {
+				t.Errorf("%s: sampled %v, expected %v", test.name, out, test.expected)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package scw
+
+import "io"
+
+// RepairCRC copies an scw File from r to w with the checksum of every chunk recomputed,
+// the chunks are copied as is (they are not decoded) and the repaired ones are returned
+func RepairCRC(r io.Reader, w io.Writer) ([]CRCError, error) {
+	decoder := NewDecoder(r)
+	writer := NewStreamWriter(w)
+
+	var repaired []CRCError
+
+	writer.WriteStringChars(Magic)
+
+	for {
+		chunk, err := decoder.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return repaired, err
+		}
+
+		body, stored, err := decoder.RawBody()
+		if err != nil {
+			return repaired, err
+		}
+
+		computed := checksum(chunk.Tag, body)
+		if computed != stored {
+			repaired = append(repaired, CRCError{Tag: chunk.Tag, Offset: chunk.Offset, Stored: stored, Computed: computed})
+		}
+
+		writer.WriteU32(chunk.Length)
+		writer.WriteStringChars(chunk.Tag)
+		writer.WriteBytes(body)
+		writer.WriteU32(computed)
+	}
+
+	return repaired, writer.Err()
+}
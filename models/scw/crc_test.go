@@ -0,0 +1,100 @@
+package scw
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// corruptCRC returns a copy of data with the checksum of the chunk with index changed, and the chunk
+func corruptCRC(t *testing.T, data []byte, index int) ([]byte, *Chunk) {
+	t.Helper()
+
+	decoder := NewDecoder(bytes.NewReader(data))
+	for {
+		chunk, err := decoder.Next()
+		if err != nil {
+			t.Fatalf("no chunk %d: %v", index, err)
+		}
+
+		if chunk.Index == index {
+			corrupted := append([]byte(nil), data...)
+			// after the length, the tag and the body
+			corrupted[chunk.Offset+8+int64(chunk.Length)] ^= 0xff
+			return corrupted, chunk
+		}
+
+		if err = decoder.Skip(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerifyCRC(t *testing.T) {
+	data := corpus(t)["v2.scw"]
+	corrupted, chunk := corruptCRC(t, data, 1)
+
+	file := New(corrupted)
+	var crcErr *CRCError
+	if err := file.LoadWithOptions(LoadOptions{VerifyCRC: true}); !errors.As(err, &crcErr) {
+		t.Fatalf("strict Load returned %v, expected a *CRCError", err)
+	}
+	if crcErr.Tag != chunk.Tag || crcErr.Offset != chunk.Offset || crcErr.Stored == crcErr.Computed {
+		t.Fatalf("got %v for chunk %s at offset %d", crcErr, chunk.Tag, chunk.Offset)
+	}
+
+	// skipped chunks are verified too
+	decoder := NewDecoder(bytes.NewReader(corrupted))
+	decoder.VerifyCRC = true
+	var err error
+	for err == nil {
+		if _, err = decoder.Next(); err == nil {
+			err = decoder.Skip()
+		}
+	}
+	if !errors.As(err, &crcErr) {
+		t.Fatalf("strict Skip returned %v, expected a *CRCError", err)
+	}
+
+	// checksums are not verified by default
+	if err := New(corrupted).Load(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepairCRC(t *testing.T) {
+	for name, data := range corpus(t) {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			repaired, err := RepairCRC(bytes.NewReader(data), &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(repaired) != 0 || !bytes.Equal(out.Bytes(), data) {
+				t.Fatalf("repaired %v in a valid file", repaired)
+			}
+
+			corrupted, chunk := corruptCRC(t, data, 2)
+
+			out.Reset()
+			if repaired, err = RepairCRC(bytes.NewReader(corrupted), &out); err != nil {
+				t.Fatal(err)
+			}
+			if len(repaired) != 1 || repaired[0].Tag != chunk.Tag || repaired[0].Offset != chunk.Offset {
+				t.Fatalf("repaired %v, expected chunk %s at offset %d", repaired, chunk.Tag, chunk.Offset)
+			}
+			if !bytes.Equal(out.Bytes(), data) {
+				t.Fatal("repaired file differs from the valid one")
+			}
+		})
+	}
+}
+
+func TestRepairCRCTruncated(t *testing.T) {
+	data := corpus(t)["v2.scw"]
+
+	if _, err := RepairCRC(bytes.NewReader(data[:len(data)/2]), io.Discard); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("RepairCRC returned %v, expected io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,392 @@
+package scw
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+var (
+	// ErrNoChunk Decode or Skip was called before Next or after the chunk was consumed
+	ErrNoChunk = errors.New("no scw chunk to read")
+)
+
+// Chunk is the header of an scw chunk: [u32 length][tag][body][u32 crc32(tag + body)]
+type Chunk struct {
+	Tag    string
+	Length uint32 // body length, without the tag and the checksum
+	Offset int64  // offset of the chunk (its length) in the file
+	Index  int    // position of the chunk among the chunks of the file, 0 being HEAD
+}
+
+// CRCError the stored checksum of a chunk does not match its tag and body
+type CRCError struct {
+	Tag              string
+	Offset           int64
+	Stored, Computed uint32
+}
+
+func (e *CRCError) Error() string {
+	return fmt.Sprintf("scw chunk %s at offset %d: crc32 mismatch (stored %08x, computed %08x)", e.Tag, e.Offset, e.Stored, e.Computed)
+}
+
+// checksum computes the crc32 of a chunk the same way EncodeSc3dProperty does
+func checksum(tag string, body []byte) uint32 {
+	hash := crc32.NewIEEE()
+	_, _ = hash.Write([]byte(tag))
+	_, _ = hash.Write(body)
+	return hash.Sum32()
+}
+
+// Decoder reads an scw File one chunk at a time from an io.Reader,
+// only the body of the current chunk is kept in memory when it is decoded
+//
+//	for {
+//		chunk, err := decoder.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//		if chunk.Tag == "NODE" {
+//			nodes, err := decoder.Decode()
+//		}
+//	}
+type Decoder struct {
+	// VerifyCRC makes Decode and Skip return a *CRCError for chunks with a wrong checksum
+	VerifyCRC bool
+	// KeepRawSamples keeps the int16 samples of every SourceArray, see SourceArray.Raw
+	KeepRawSamples bool
+
+	r      *bufio.Reader
+	file   *File
+	offset int64
+
+	started bool
+	done    bool
+	count   int    // chunks read so far
+	chunk   *Chunk // current chunk, nil once its body was decoded or skipped
+}
+
+// NewDecoder returns a Decoder reading from r, decoded chunks are collected in File
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r:    bufio.NewReader(r),
+		file: &File{MinorVersion: MinorVersionAuto},
+	}
+}
+
+// File returns the File built from the decoded chunks, the MinorVersion of v0 files
+// is detected (see MinorVersionAuto) unless it is set before decoding GEOM chunks,
+// it is set to 5 when WEND is read if no geometry told it
+func (d *Decoder) File() *File {
+	return d.file
+}
+
+// Next reads the header of the next chunk, the body of the current one is skipped if it was not decoded
+//
+// io.EOF is returned after the WEND chunk
+func (d *Decoder) Next() (*Chunk, error) {
+	if d.chunk != nil {
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.done {
+		return nil, io.EOF
+	}
+
+	if !d.started {
+		magic := make([]byte, len(Magic))
+		if err := d.read(magic); err != nil {
+			return nil, &DecodeError{Offset: d.offset, Err: err}
+		}
+		if string(magic) != Magic {
+			return nil, &DecodeError{Err: ErrInvalidSCWMagic}
+		}
+		d.started = true
+	}
+
+	offset := d.offset
+
+	var header [8]byte
+	if err := d.read(header[:]); err != nil {
+		return nil, &DecodeError{Offset: d.offset, Err: err}
+	}
+
+	d.chunk = &Chunk{
+		Length: binary.BigEndian.Uint32(header[:4]),
+		Tag:    string(header[4:]),
+		Offset: offset,
+		Index:  d.count,
+	}
+	d.count++
+
+	if d.chunk.Tag == "WEND" {
+		d.done = true
+
+		switch {
+		case d.file.Version != 0:
+			d.file.MinorVersion = 0
+		case d.file.MinorVersion == MinorVersionAuto:
+			// no geometry told the weight sizes apart, the size does not matter then
+			d.file.MinorVersion = 5
+		}
+	}
+
+	return d.chunk, nil
+}
+
+// read fills buf, a file ending in the middle of a chunk (or without WEND) is an io.ErrUnexpectedEOF
+func (d *Decoder) read(buf []byte) error {
+	n, err := io.ReadFull(d.r, buf)
+	d.offset += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Skip discards the body of the current chunk, it is still read to check its checksum if VerifyCRC is set
+func (d *Decoder) Skip() error {
+	if d.chunk == nil {
+		return ErrNoChunk
+	}
+
+	if d.VerifyCRC {
+		_, err := d.Body()
+		return err
+	}
+
+	// the body and its checksum
+	chunk := d.chunk
+	n := int64(chunk.Length) + 4
+	d.chunk = nil
+
+	copied, err := io.CopyN(io.Discard, d.r, n)
+	d.offset += copied
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return &DecodeError{Chunk: chunk.Tag, Offset: d.offset, Err: err}
+	}
+	return nil
+}
+
+// Body reads the raw body of the current chunk, see RawBody to get the stored checksum
+func (d *Decoder) Body() ([]byte, error) {
+	chunk := d.chunk
+
+	body, stored, err := d.RawBody()
+	if err != nil {
+		return nil, err
+	}
+
+	if d.VerifyCRC {
+		if computed := checksum(chunk.Tag, body); computed != stored {
+			return nil, &CRCError{Tag: chunk.Tag, Offset: chunk.Offset, Stored: stored, Computed: computed}
+		}
+	}
+
+	return body, nil
+}
+
+// RawBody reads the body of the current chunk and its stored checksum, which is never verified
+func (d *Decoder) RawBody() ([]byte, uint32, error) {
+	chunk := d.chunk
+	if chunk == nil {
+		return nil, 0, ErrNoChunk
+	}
+	d.chunk = nil
+
+	// the body is copied instead of allocated up front so a corrupted length
+	// can not allocate more than what is left to read
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, d.r, int64(chunk.Length)+4)
+	d.offset += n
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, 0, &DecodeError{Chunk: chunk.Tag, Offset: d.offset, Err: err}
+	}
+	data := buf.Bytes()
+
+	return data[:chunk.Length], binary.BigEndian.Uint32(data[chunk.Length:]), nil
+}
+
+// Decode reads the body of the current chunk into File and returns what it decoded:
+// *Header, *Material, *Geometry, *Camera3D, []Node, an UnknownChunk (a copy of the one
+// appended to File.UnknownChunks) or nil for WEND
+func (d *Decoder) Decode() (any, error) {
+	chunk := d.chunk
+
+	body, err := d.Body()
+	if err != nil {
+		return nil, err
+	}
+
+	reader := NewReader(body)
+	reader.KeepRawSamples = d.KeepRawSamples
+
+	return d.file.decodeChunk(chunk, reader)
+}
+
+// decodeChunk decodes the body of a chunk, reader must start at the body
+//
+// errors are returned as a *DecodeError with the offset in the file where decoding stopped
+func (f *File) decodeChunk(chunk *Chunk, reader *Reader) (any, error) {
+	prop, err := f.decodeBody(chunk, reader)
+	if err != nil {
+		field := chunk.Tag
+		// decodeBody only appends decoded chunks, so the index of the failed one is the count of the decoded ones
+		switch chunk.Tag {
+		case "MATE":
+			field += fmt.Sprintf("[%d]", len(f.Materials))
+		case "GEOM":
+			field += fmt.Sprintf("[%d]", len(f.Geometries))
+		case "CAME":
+			field += fmt.Sprintf("[%d]", len(f.Cameras))
+		}
+
+		if fe, ok := err.(*fieldError); ok {
+			field, err = joinPath(field, fe.path), fe.err
+		}
+
+		return nil, &DecodeError{
+			Chunk: chunk.Tag,
+			// the body starts after the length and the tag
+			Offset: chunk.Offset + 8 + int64(reader.offset),
+			Field:  field,
+			Err:    err,
+		}
+	}
+
+	if chunk.Tag != "WEND" {
+		f.ChunkOrder = append(f.ChunkOrder, chunk.Tag)
+	}
+	return prop, nil
+}
+
+func (f *File) decodeBody(chunk *Chunk, reader *Reader) (prop any, err error) {
+	reader.SkipBytes = int(chunk.Length)
+	start := reader.offset
+
+	switch chunk.Tag {
+	case "HEAD":
+		if err = f.Header.Decode(reader); err != nil {
+			return
+		}
+		prop = &f.Header
+	case "MATE":
+		material := new(Material)
+		material.SCWFile = f
+		if err = material.Decode(reader); err != nil {
+			return
+		}
+		f.Materials = append(f.Materials, material)
+		prop = material
+	case "GEOM":
+		geometry := new(Geometry)
+		geometry.SCWFile = f
+		if f.Version == 0 && f.MinorVersion == MinorVersionAuto {
+			f.detectMinorVersion(geometry, reader, start, int(chunk.Length))
+		}
+		if err = geometry.Decode(reader); err != nil {
+			return
+		}
+		f.Geometries = append(f.Geometries, geometry)
+		prop = geometry
+	case "CAME":
+		camera := new(Camera3D)
+		if err = camera.Decode(reader); err != nil {
+			return
+		}
+		f.Cameras = append(f.Cameras, camera)
+		prop = camera
+	case "NODE":
+		var nodesCount uint16
+		if nodesCount, err = reader.ReadU16(); err != nil {
+			return
+		}
+		f.Nodes = make([]Node, nodesCount)
+		for i := range nodesCount {
+			f.Nodes[i].SCWFile = f
+			offset, left := reader.offset, reader.SkipBytes
+			if err = f.Nodes[i].Decode(reader); errors.Is(err, ErrLightInstance) {
+				// the nodes can not be decoded past the light, the rest of the chunk is kept as is
+				reader.offset, reader.SkipBytes = offset, left
+				var data []byte
+				if data, err = reader.Read(left); err != nil {
+					return
+				}
+				f.Nodes = f.Nodes[:i]
+				f.RawNodes = &RawNodes{Count: nodesCount - i, Data: append([]byte(nil), data...)}
+				break
+			} else if err != nil {
+				return nil, withField(err, "[%d]", i)
+			}
+		}
+		prop = f.Nodes
+	case "WEND":
+		reader.SkipBytes = 0
+	default:
+		return f.decodeUnknown(chunk, reader)
+	}
+
+	if reader.SkipBytes != 0 {
+		return nil, fmt.Errorf("%w: %d bytes left", ErrTrailingBytes, reader.SkipBytes)
+	}
+
+	return
+}
+
+// decodeUnknown keeps the body of a chunk in UnknownChunks
+func (f *File) decodeUnknown(chunk *Chunk, reader *Reader) (any, error) {
+	body, err := reader.Read(int(chunk.Length))
+	if err != nil {
+		return nil, err
+	}
+
+	unknown := UnknownChunk{
+		Tag:      chunk.Tag,
+		Body:     append([]byte(nil), body...),
+		Position: chunk.Index,
+	}
+	// a copy is returned, a pointer would be invalidated by the next append
+	f.UnknownChunks = append(f.UnknownChunks, unknown)
+	return unknown, nil
+}
+
+// detectMinorVersion sets the minor version of a v0 File by decoding a GEOM chunk with word
+// then byte weights, the one that decodes the whole chunk is kept. The minor version stays
+// MinorVersionAuto for geometries without weights, both sizes decode them
+//
+// reader is rewound to start for the geometry to be decoded again
+func (f *File) detectMinorVersion(geometry *Geometry, reader *Reader, start, length int) {
+	defer func() {
+		reader.offset, reader.SkipBytes = start, length
+		*geometry = Geometry{SCWFile: f}
+	}()
+
+	for _, minor := range []int{5, 0} {
+		reader.offset, reader.SkipBytes = start, length
+		*geometry = Geometry{SCWFile: f}
+
+		f.MinorVersion = minor
+		if geometry.Decode(reader) == nil && reader.SkipBytes == 0 {
+			if len(geometry.SkinWeights) == 0 {
+				f.MinorVersion = MinorVersionAuto
+			}
+			return
+		}
+	}
+
+	// neither fits, the error of the newest one is reported
+	f.MinorVersion = 5
+}
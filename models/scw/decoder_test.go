@@ -0,0 +1,186 @@
+package scw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/iotest"
+)
+
+// corpus returns the scw files of testdata by name
+func corpus(t testing.TB) map[string][]byte {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.scw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no scw file in testdata")
+	}
+
+	files := make(map[string][]byte)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Base(path)] = data
+	}
+	return files
+}
+
+func load(t *testing.T, data []byte, opts LoadOptions) *File {
+	t.Helper()
+
+	file := New(data)
+	if err := file.LoadWithOptions(opts); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func marshal(t *testing.T, file *File) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecoderSkip(t *testing.T) {
+	for name, data := range corpus(t) {
+		t.Run(name, func(t *testing.T) {
+			decoder := NewDecoder(bytes.NewReader(data))
+			decoder.VerifyCRC = true
+
+			var (
+				tags   []string
+				offset int64
+			)
+			for {
+				chunk, err := decoder.Next()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+
+				if chunk.Offset <= offset || chunk.Index != len(tags) {
+					t.Fatalf("chunk %s at offset %d with index %d after offset %d", chunk.Tag, chunk.Offset, chunk.Index, offset)
+				}
+				offset = chunk.Offset
+
+				if err = decoder.Skip(); err != nil {
+					t.Fatal(err)
+				}
+				tags = append(tags, chunk.Tag)
+			}
+
+			expected := append(load(t, data, LoadOptions{}).ChunkOrder, "WEND")
+			if !slices.Equal(tags, expected) {
+				t.Fatalf("skipped %v, expected %v", tags, expected)
+			}
+
+			if _, err := decoder.Next(); err != io.EOF {
+				t.Fatalf("Next after WEND returned %v, expected io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestDecoderDecode(t *testing.T) {
+	for name, data := range corpus(t) {
+		t.Run(name, func(t *testing.T) {
+			// one byte at a time so nothing relies on the data being read at once
+			decoder := NewDecoder(iotest.OneByteReader(bytes.NewReader(data)))
+
+			for {
+				if _, err := decoder.Next(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+
+				if _, err := decoder.Decode(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			decoded, loaded := marshal(t, decoder.File()), marshal(t, load(t, data, LoadOptions{}))
+			if !bytes.Equal(decoded, loaded) {
+				t.Fatalf("decoded file differs from the loaded one:\n%s\n%s", decoded, loaded)
+			}
+		})
+	}
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	data := corpus(t)["v2.scw"]
+
+	for _, size := range []int{0, 2, len(Magic), len(Magic) + 6, len(data) / 2, len(data) - 1} {
+		file := New(data[:size])
+
+		var decodeErr *DecodeError
+		if err := file.Load(); !errors.As(err, &decodeErr) || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("%d bytes: got %v, expected a *DecodeError wrapping io.ErrUnexpectedEOF", size, err)
+		}
+	}
+}
+
+func TestDetectMinorVersion(t *testing.T) {
+	files := corpus(t)
+
+	for name, minor := range map[string]int{"v0_minor0.scw": 0, "v0_minor5.scw": 5, "v1.scw": 0, "v2.scw": 0} {
+		if file := load(t, files[name], LoadOptions{}); file.MinorVersion != minor {
+			t.Fatalf("%s: minor version %d, expected %d", name, file.MinorVersion, minor)
+		}
+	}
+
+	// the minor version set before loading is used, word weights do not decode byte weights
+	file := New(files["v0_minor0.scw"])
+	file.MinorVersion = 5
+	var decodeErr *DecodeError
+	if err := file.Load(); !errors.As(err, &decodeErr) || decodeErr.Chunk != "GEOM" {
+		t.Fatalf("Load with minor 5 returned %v, expected a GEOM *DecodeError", err)
+	}
+
+	// without weights both sizes decode the geometries, 5 is used
+	file = load(t, files["v0_minor0.scw"], LoadOptions{})
+	for _, geometry := range file.Geometries {
+		geometry.SkinWeights = nil
+	}
+	encoded, err := file.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file = load(t, encoded, LoadOptions{}); file.MinorVersion != 5 {
+		t.Fatalf("minor version %d without weights, expected 5", file.MinorVersion)
+	}
+}
+
+func TestDetectedMinorVersionJSON(t *testing.T) {
+	data := corpus(t)["v0_minor0.scw"]
+
+	encoded := marshal(t, load(t, data, LoadOptions{}))
+	if !bytes.Contains(encoded, []byte(`"MinorVersion":0`)) {
+		t.Fatalf("JSON does not hold the detected minor version:\n%s", encoded)
+	}
+
+	file := New(encoded)
+	if err := file.LoadJSON(); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := file.Encode(); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(out, data) {
+		t.Fatal("file encoded from JSON differs from the loaded one")
+	}
+}
@@ -0,0 +1,100 @@
+package scw
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedInstance the type of a node instance is unknown or not supported
+	ErrUnsupportedInstance = errors.New("unsupported node instance type")
+	// ErrLightInstance the layout of LIGH node instances is not known, the nodes from the first one
+	// with a light instance on are kept in Scene.RawNodes
+	ErrLightInstance = errors.New("light node instances are not supported")
+	// ErrIndexBufferSize the size of the indices of an IndexArray is not 1, 2 or 4 bytes
+	ErrIndexBufferSize = errors.New("unsupported index buffer size")
+	// ErrByteWeight a skin weight does not fit the byte weights of v0 files before minor version 5
+	ErrByteWeight = errors.New("skin weight does not fit in a byte")
+	// ErrTrailingBytes a chunk was decoded without reading its whole body
+	ErrTrailingBytes = errors.New("scw chunk not fully decoded")
+	// ErrSourceStride the stride of a SourceArray is 0 or its data is not a multiple of it
+	ErrSourceStride = errors.New("invalid source array stride")
+)
+
+// DecodeError is returned when a File can not be decoded, Err is the cause
+type DecodeError struct {
+	Chunk  string // tag of the chunk, empty for errors outside of chunks (the magic)
+	Offset int64  // offset in the file where decoding failed
+	// Field is the path of the value that failed to decode, starting with the chunk
+	// and its index among the chunks with the same tag: GEOM[3].Vertices[1].Data[512]
+	Field string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	where := "file"
+	if len(e.Field) != 0 {
+		where = e.Field
+	} else if len(e.Chunk) != 0 {
+		where = "chunk " + e.Chunk
+	}
+	return fmt.Sprintf("scw decode: %s at offset %d: %v", where, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// EncodeError is returned when a File can not be encoded, Err is the cause
+type EncodeError struct {
+	Chunk string
+	Err   error
+}
+
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("scw encode: chunk %s: %v", e.Chunk, e.Err)
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError carries the path of the field a decode error happened in up to decodeChunk
+type fieldError struct {
+	path string
+	err  error
+}
+
+func (e *fieldError) Error() string {
+	return e.path + ": " + e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// joinPath joins two field paths, indices are not separated by a dot: Vertices + [1] = Vertices[1]
+func joinPath(parent, child string) string {
+	if len(parent) == 0 || strings.HasPrefix(child, "[") {
+		return parent + child
+	}
+	if len(child) == 0 {
+		return parent
+	}
+	return parent + "." + child
+}
+
+// withField prefixes the field path of err, nil stays nil
+func withField(err error, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+
+	field := fmt.Sprintf(format, args...)
+	if fe, ok := err.(*fieldError); ok {
+		fe.path = joinPath(field, fe.path)
+		return fe
+	}
+	return &fieldError{path: field, err: err}
+}
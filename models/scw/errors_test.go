@@ -0,0 +1,149 @@
+package scw
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestEncodeInvalidStride(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		modify func(src *SourceArray)
+	}{
+		{"zero stride", func(src *SourceArray) { src.Stride = 0 }},
+		{"data not a multiple of the stride", func(src *SourceArray) { src.Data = src.Data[:len(src.Data)-1] }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			file := load(t, corpus(t)["v2.scw"], LoadOptions{})
+			test.modify(&file.Geometries[0].Vertices[0])
+
+			var encodeErr *EncodeError
+			if _, err := file.Encode(); !errors.As(err, &encodeErr) || encodeErr.Chunk != "GEOM" || !errors.Is(err, ErrSourceStride) {
+				t.Fatalf("Encode returned %v, expected a GEOM *EncodeError wrapping ErrSourceStride", err)
+			}
+
+			if err := file.EncodeTo(io.Discard); !errors.Is(err, ErrSourceStride) {
+				t.Fatalf("EncodeTo returned %v, expected ErrSourceStride", err)
+			}
+		})
+	}
+}
+
+// loadEncode loads data and encodes it back, every error must be typed and nothing may panic
+func loadEncode(t *testing.T, data []byte) {
+	t.Helper()
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("panic on %x: %v", data, r)
+		}
+	}()
+
+	file := New(data)
+	if err := file.Load(); err != nil {
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Fatalf("Load returned %T (%v), expected a *DecodeError", err, err)
+		}
+		return
+	}
+
+	if _, err := file.Encode(); err != nil {
+		var encodeErr *EncodeError
+		if !errors.As(err, &encodeErr) {
+			t.Fatalf("Encode returned %T (%v), expected an *EncodeError", err, err)
+		}
+	}
+}
+
+func TestByteFlips(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for name, data := range corpus(t) {
+		t.Run(name, func(t *testing.T) {
+			for range 2000 {
+				flipped := append([]byte(nil), data...)
+				for range 1 + random.Intn(4) {
+					flipped[len(Magic)+random.Intn(len(flipped)-len(Magic))] ^= byte(1 + random.Intn(255))
+				}
+				loadEncode(t, flipped)
+			}
+		})
+	}
+}
+
+func FuzzLoadEncode(f *testing.F) {
+	for _, data := range corpus(f) {
+		f.Add(data)
+	}
+
+	f.Fuzz(loadEncode)
+}
+
+// truncatedGeometry returns a file whose first geometry is cut in the data of its second source array,
+// the offset in the file where decoding stops and the path of the field
+func truncatedGeometry(t *testing.T) ([]byte, int64, string) {
+	t.Helper()
+
+	file := load(t, corpus(t)["v2.scw"], LoadOptions{})
+	geometry := file.Geometries[0]
+	if len(geometry.Vertices) < 2 {
+		t.Fatalf("geometry %s has %d source arrays", geometry.Name, len(geometry.Vertices))
+	}
+
+	writer := NewWriter()
+	geometry.Encode(writer)
+	body := writer.Bytes()
+
+	writer = NewWriter()
+	geometry.Vertices[1].Encode(writer)
+	source := writer.Bytes()
+
+	// the data follows the sample count at the end of the source array
+	data := bytes.Index(body, source) + len(source) - 2*len(geometry.Vertices[1].Data)
+
+	writer = NewWriter()
+	writer.WriteStringChars(Magic)
+	EncodeSc3dProperty(&file.Header, writer)
+	chunk := len(writer.Bytes())
+	EncodeSc3dProperty(unknownProperty{&UnknownChunk{Tag: "GEOM", Body: body[:data+3]}}, writer)
+	EncodeSc3dProperty(&Wend{}, writer)
+
+	return writer.Bytes(), int64(chunk + 8 + data), "GEOM[0].Vertices[1].Data"
+}
+
+func TestDecodeErrorField(t *testing.T) {
+	data, offset, field := truncatedGeometry(t)
+
+	var decodeErr *DecodeError
+	if err := New(data).Load(); !errors.As(err, &decodeErr) {
+		t.Fatalf("Load returned %v, expected a *DecodeError", err)
+	}
+
+	if decodeErr.Chunk != "GEOM" || decodeErr.Field != field || decodeErr.Offset != offset {
+		t.Fatalf("got chunk %s, field %s at offset %d, expected GEOM, %s at offset %d",
+			decodeErr.Chunk, decodeErr.Field, decodeErr.Offset, field, offset)
+	}
+
+	if !errors.Is(decodeErr, io.ErrShortBuffer) {
+		t.Fatalf("%v does not wrap io.ErrShortBuffer", decodeErr)
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	for _, test := range []struct {
+		parent, child, path string
+	}{
+		{"", "Vertices", "Vertices"},
+		{"GEOM[3]", "Vertices", "GEOM[3].Vertices"},
+		{"Vertices", "[1]", "Vertices[1]"},
+		{"Vertices[1]", "", "Vertices[1]"},
+	} {
+		if path := joinPath(test.parent, test.child); path != test.path {
+			t.Fatalf("joinPath(%q, %q) = %q, expected %q", test.parent, test.child, path, test.path)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package scw
 
 import (
 	"fmt"
+	"math"
 )
 
 type Geometry struct {
@@ -25,11 +26,18 @@ func (g *Geometry) Tag() string {
 	return "GEOM"
 }
 
+// Weight of a vertex, Weights are bytes (0-255) in files with ByteWeights and uint16 (0-65535) in the others
 type Weight struct {
 	Joints  [4]byte
 	Weights [4]uint16
 }
 
+// ByteWeights reports if the skin weights of an scw version are stored on a byte,
+// v0 files before minor version 5 do, an unknown minor version is taken as 5
+func ByteWeights(scwVersion uint16, scwMinorVersion int) bool {
+	return scwVersion == 0 && scwMinorVersion != MinorVersionAuto && scwMinorVersion < 5
+}
+
 func (w *Weight) Decode(reader *Reader, scwVersion uint16, scwMinorVersion int) (err error) {
 	for i := range 4 {
 		if w.Joints[i], err = reader.ReadU8(); err != nil {
@@ -39,9 +47,7 @@ func (w *Weight) Decode(reader *Reader, scwVersion uint16, scwMinorVersion int)
 
 	var readFn func() (uint16, error)
 
-	// scw v0 needs more work
-	// (it's support is planned)
-	if scwVersion == 0 && scwMinorVersion != 5 {
+	if ByteWeights(scwVersion, scwMinorVersion) {
 		readFn = func() (uint16, error) {
 			res, err := reader.ReadU8()
 			return uint16(res), err
@@ -63,9 +69,14 @@ func (w *Weight) Encode(writer *Writer, scwVersion uint16, scwMinorVersion int)
 	writer.WriteBytes(w.Joints[:])
 
 	var writeFn func(uint16)
-	_ = writeFn
 
-	if scwVersion == 0 && scwMinorVersion != 5 {
+	if ByteWeights(scwVersion, scwMinorVersion) {
+		for i, val := range w.Weights {
+			if val > math.MaxUint8 {
+				writer.Fail(fmt.Errorf("%w: Weights[%d] = %d", ErrByteWeight, i, val))
+				return
+			}
+		}
 		writeFn = func(val uint16) {
 			writer.WriteU8(byte(val))
 		}
@@ -85,6 +96,28 @@ type SourceArray struct {
 	Stride      byte      // stride(Color) / element size
 	Scale       float32   // this can always be 0?? (not saying it is)
 	Data        []float64 // vertex/coordinate data?
+	// Raw are the int16 samples of Data, kept when the File is loaded with KeepRawSamples
+	// and written back as is by Encode for the values of Data that did not change
+	Raw []int16 `json:",omitempty"`
+}
+
+// QuantizationScale returns the smallest scale that fits data in the int16 range of a SourceArray
+func QuantizationScale(data []float64) float32 {
+	maxAbs := 0.0
+	for _, v := range data {
+		maxAbs = max(maxAbs, math.Abs(v))
+	}
+
+	if maxAbs == 0 {
+		return 1
+	}
+
+	return float32(maxAbs / math.MaxInt16)
+}
+
+// IsPosition reports if the source array holds vertex positions, v2 files name it POSITION while older ones use VERTEX
+func (s *SourceArray) IsPosition() bool {
+	return s.Name == "POSITION" || s.Name == "VERTEX"
 }
 
 func (s *SourceArray) Decode(reader *Reader) (err error) {
@@ -112,14 +145,26 @@ func (s *SourceArray) Decode(reader *Reader) (err error) {
 
 	count *= uint32(s.Stride)
 
+	if err = reader.Ensure(uint64(count), 2); err != nil {
+		return withField(err, "Data")
+	}
+
 	s.Data = make([]float64, count)
 
+	s.Raw = nil
+	if reader.KeepRawSamples {
+		s.Raw = make([]int16, count)
+	}
+
 	var val int16
 	for i := range count {
 		if val, err = reader.ReadI16(); err != nil {
-			return
+			return withField(err, "Data[%d]", i)
 		}
 		s.Data[i] = float64(val) * float64(s.Scale)
+		if s.Raw != nil {
+			s.Raw[i] = val
+		}
 	}
 
 	return
@@ -131,16 +176,33 @@ func (s *SourceArray) Encode(writer *Writer) {
 	writer.WriteU8(s.Index)
 	writer.WriteU8(s.SourceIndex)
 	writer.WriteU8(s.Stride)
-	writer.WriteFloat(s.Scale)
+
+	if s.Stride == 0 {
+		writer.Fail(fmt.Errorf("%w: source %s has a stride of 0", ErrSourceStride, s.Name))
+		return
+	}
+
+	if len(s.Data)%int(s.Stride) != 0 {
+		writer.Fail(fmt.Errorf("%w: source %s has %d values for a stride of %d", ErrSourceStride, s.Name, len(s.Data), s.Stride))
+		return
+	}
+
+	scale := s.EncodeScale()
+	samples, err := s.Quantize(scale)
+	if err != nil {
+		writer.Fail(err)
+		return
+	}
+
+	writer.WriteFloat(scale)
 
 	count := uint32(len(s.Data))
 	count /= uint32(s.Stride)
 
 	writer.WriteU32(count)
 
-	for _, data := range s.Data {
-		val := int16(data / float64(s.Scale))
-		writer.WriteI16(val)
+	for _, sample := range samples {
+		writer.WriteI16(sample)
 	}
 
 }
@@ -170,7 +232,17 @@ func (i *IndexArray) Decode(reader *Reader) (err error) {
 		return
 	}
 
-	totalIndices := 3 * i.TrianglesCount * uint32(i.InputsCount)
+	switch i.IndexBufferSize {
+	case 1, 2, 4:
+	default:
+		return fmt.Errorf("%w: %d", ErrIndexBufferSize, i.IndexBufferSize)
+	}
+
+	totalIndices := 3 * uint64(i.TrianglesCount) * uint64(i.InputsCount)
+
+	if err = reader.Ensure(totalIndices, int(i.IndexBufferSize)); err != nil {
+		return withField(err, "IndexBuffer")
+	}
 
 	i.IndexBuffer = make([]uint32, totalIndices)
 
@@ -179,21 +251,19 @@ func (i *IndexArray) Decode(reader *Reader) (err error) {
 		case 1:
 			var idx uint8
 			if idx, err = reader.ReadU8(); err != nil {
-				return
+				return withField(err, "IndexBuffer[%d]", v)
 			}
 			i.IndexBuffer[v] = uint32(idx)
 		case 2:
 			var idx uint16
 			if idx, err = reader.ReadU16(); err != nil {
-				return
+				return withField(err, "IndexBuffer[%d]", v)
 			}
 			i.IndexBuffer[v] = uint32(idx)
 		case 4:
 			if i.IndexBuffer[v], err = reader.ReadU32(); err != nil {
-				return
+				return withField(err, "IndexBuffer[%d]", v)
 			}
-		default:
-			return fmt.Errorf("unsupported index buffer size: %d", i.IndexBufferSize)
 		}
 	}
 
@@ -201,6 +271,14 @@ func (i *IndexArray) Decode(reader *Reader) (err error) {
 }
 
 func (i *IndexArray) Encode(writer *Writer) {
+	switch i.IndexBufferSize {
+	case 1, 2, 4:
+	default:
+		// this should not be reached unless manual bad modification for the model was done
+		writer.Fail(fmt.Errorf("%w: %d", ErrIndexBufferSize, i.IndexBufferSize))
+		return
+	}
+
 	writer.WriteStringUTF(i.Name)
 
 	writer.WriteU32(i.TrianglesCount)
@@ -215,9 +293,6 @@ func (i *IndexArray) Encode(writer *Writer) {
 			writer.WriteU16(uint16(i.IndexBuffer[v]))
 		case 4:
 			writer.WriteU32(i.IndexBuffer[v])
-		default:
-			// this should not be reached unless manual bad modification for the model was done
-			panic(fmt.Errorf("unsupported index buffer size: %d", i.IndexBufferSize))
 		}
 	}
 
@@ -228,7 +303,7 @@ type Matrix4x4 [4][4]float32
 func (m *Matrix4x4) Decode(reader *Reader) (err error) {
 	for i := 0; i < 16; i++ {
 		if m[i/4][i%4], err = reader.ReadFloat(); err != nil {
-			return
+			return withField(err, "[%d][%d]", i/4, i%4)
 		}
 	}
 	m.Transpose()
@@ -264,7 +339,7 @@ func (g *Geometry) Decode(reader *Reader) (err error) {
 
 	if g.SCWFile.Version <= 1 {
 		if err = g.IgnoredMatrix.Decode(reader); err != nil {
-			return
+			return withField(err, "IgnoredMatrix")
 		}
 	}
 
@@ -276,7 +351,7 @@ func (g *Geometry) Decode(reader *Reader) (err error) {
 	g.Vertices = make([]SourceArray, verticesCount)
 	for i := range g.Vertices {
 		if err = g.Vertices[i].Decode(reader); err != nil {
-			return
+			return withField(err, "Vertices[%d]", i)
 		}
 	}
 
@@ -284,7 +359,7 @@ func (g *Geometry) Decode(reader *Reader) (err error) {
 		return err
 	} else if g.HasBindMatrix {
 		if err = g.BindMatrix.Decode(reader); err != nil {
-			return
+			return withField(err, "BindMatrix")
 		}
 	}
 
@@ -298,10 +373,10 @@ func (g *Geometry) Decode(reader *Reader) (err error) {
 
 	for i := range skinsCount {
 		if g.Skins.Joints[i], err = reader.ReadUTF(); err != nil {
-			return err
+			return withField(err, "Skins.Joints[%d]", i)
 		}
 		if err = g.Skins.InverseBindMatrices[i].Decode(reader); err != nil {
-			return err
+			return withField(err, "Skins.InverseBindMatrices[%d]", i)
 		}
 	}
 
@@ -310,10 +385,15 @@ func (g *Geometry) Decode(reader *Reader) (err error) {
 		return err
 	}
 
+	// every weight has at least 4 joints and 4 weights of a byte
+	if err = reader.Ensure(uint64(skinWeightsCount), 8); err != nil {
+		return withField(err, "SkinWeights")
+	}
+
 	g.SkinWeights = make([]Weight, skinWeightsCount)
 	for i := range skinWeightsCount {
 		if err = g.SkinWeights[i].Decode(reader, g.SCWFile.Version, g.SCWFile.MinorVersion); err != nil {
-			return
+			return withField(err, "SkinWeights[%d]", i)
 		}
 	}
 
@@ -325,7 +405,7 @@ func (g *Geometry) Decode(reader *Reader) (err error) {
 	g.Materials = make([]IndexArray, indexesCount)
 	for i := range indexesCount {
 		if err = g.Materials[i].Decode(reader); err != nil {
-			return
+			return withField(err, "Materials[%d]", i)
 		}
 	}
 
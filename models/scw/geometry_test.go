@@ -0,0 +1,141 @@
+package scw
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// TestCorpusRoundTrip decodes and encodes every file of testdata, which must be encoded back byte for byte
+func TestCorpusRoundTrip(t *testing.T) {
+	for name, data := range corpus(t) {
+		for _, raw := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/raw=%t", name, raw), func(t *testing.T) {
+				file := load(t, data, LoadOptions{VerifyCRC: true, KeepRawSamples: raw})
+
+				encoded, err := file.Encode()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(encoded, data) {
+					t.Fatalf("encoded file differs from %s (%d bytes, expected %d)", name, len(encoded), len(data))
+				}
+			})
+		}
+	}
+}
+
+func TestByteWeights(t *testing.T) {
+	for _, test := range []struct {
+		version uint16
+		minor   int
+		bytes   bool
+	}{
+		{0, 0, true},
+		{0, 4, true},
+		{0, 5, false},
+		{0, MinorVersionAuto, false},
+		{1, 0, false},
+		{2, 0, false},
+	} {
+		if bytes := ByteWeights(test.version, test.minor); bytes != test.bytes {
+			t.Fatalf("ByteWeights(%d, %d) = %t", test.version, test.minor, bytes)
+		}
+	}
+}
+
+func TestWeightRoundTrip(t *testing.T) {
+	weight := Weight{Joints: [4]byte{0, 1, 2, 3}, Weights: [4]uint16{200, 55, 0, 0}}
+
+	for _, test := range []struct {
+		minor int
+		size  int
+	}{
+		{0, 8},
+		{5, 12},
+	} {
+		writer := NewWriter()
+		weight.Encode(writer, 0, test.minor)
+		if len(writer.Bytes()) != test.size {
+			t.Fatalf("minor %d: %d bytes encoded, expected %d", test.minor, len(writer.Bytes()), test.size)
+		}
+
+		var decoded Weight
+		if err := decoded.Decode(NewReader(writer.Bytes()), 0, test.minor); err != nil {
+			t.Fatal(err)
+		}
+		if decoded != weight {
+			t.Fatalf("minor %d: decoded %+v, expected %+v", test.minor, decoded, weight)
+		}
+	}
+
+	weight.Weights[0] = 256
+	writer := NewWriter()
+	if weight.Encode(writer, 0, 0); !errors.Is(writer.Err(), ErrByteWeight) {
+		t.Fatalf("Encode failed with %v, expected ErrByteWeight", writer.Err())
+	}
+}
+
+func TestV0Weights(t *testing.T) {
+	byteWeights := load(t, corpus(t)["v0_minor0.scw"], LoadOptions{})
+	if len(byteWeights.Geometries[0].SkinWeights) == 0 {
+		t.Fatal("v0_minor0.scw has no skin weights")
+	}
+
+	// byte weights fit in words
+	byteWeights.MinorVersion = 5
+	encoded, err := byteWeights.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := load(t, encoded, LoadOptions{})
+	if reloaded.MinorVersion != 5 || !slices.Equal(reloaded.Geometries[0].SkinWeights, byteWeights.Geometries[0].SkinWeights) {
+		t.Fatalf("weights %v of minor %d, expected %v", reloaded.Geometries[0].SkinWeights, reloaded.MinorVersion, byteWeights.Geometries[0].SkinWeights)
+	}
+
+	// word weights do not fit in bytes
+	wordWeights := load(t, corpus(t)["v0_minor5.scw"], LoadOptions{})
+	wordWeights.MinorVersion = 0
+	if _, err = wordWeights.Encode(); !errors.Is(err, ErrByteWeight) {
+		t.Fatalf("Encode returned %v, expected ErrByteWeight", err)
+	}
+}
+
+func TestV0MaterialAndHeader(t *testing.T) {
+	for _, name := range []string{"v0_minor0.scw", "v0_minor5.scw"} {
+		file := load(t, corpus(t)[name], LoadOptions{})
+
+		// the trailing header byte is read by chunk length in every version, the v0 fixtures have it
+		if file.Unknown == -1 {
+			t.Fatalf("%s: header byte not decoded", name)
+		}
+
+		if len(file.Materials) == 0 || len(file.Materials[0].Name) == 0 || file.Materials[0].Variables.Unk2 != "" {
+			t.Fatalf("%s: materials %+v", name, file.Materials)
+		}
+	}
+
+	// v0 materials have the v1 layout, without the Unk2 string of v2
+	file := load(t, corpus(t)["v0_minor5.scw"], LoadOptions{})
+	file.Unknown = -1
+	file.Materials[0].Variables.Unk2 = "unk2"
+
+	reloaded := load(t, encode(t, file), LoadOptions{})
+	if reloaded.Unknown != -1 {
+		t.Fatalf("header byte %d, expected none", reloaded.Unknown)
+	}
+
+	file.Materials[0].Variables.Unk2 = ""
+	if a, b := marshal(t, file), marshal(t, reloaded); !bytes.Equal(a, b) {
+		t.Fatalf("reloaded v0 file differs:\n%s\n%s", a, b)
+	}
+
+	v0 := encode(t, file)
+	file.Version = 1
+	if v1 := encode(t, file); len(v1) != len(v0) {
+		t.Fatalf("v0 file is %d bytes, v1 is %d, expected the same layout", len(v0), len(v1))
+	}
+}
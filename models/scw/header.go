@@ -1,5 +1,8 @@
 package scw
 
+// DefaultFrameRate is used for files without animations, scw always stores a frame rate
+const DefaultFrameRate = 30
+
 type Header struct {
 	Version       uint16
 	FrameRate     uint16
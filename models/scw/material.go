@@ -1,5 +1,7 @@
 package scw
 
+import "math"
+
 type Material struct {
 	SCWFile    *File `json:"-"`
 	Name       string
@@ -40,7 +42,7 @@ func (v *Variable) Decode(reader *Reader) error {
 			return err
 		}
 	} else if err = v.Color.Decode(reader); err != nil {
-		return err
+		return withField(err, "Color")
 	}
 	return nil
 }
@@ -56,6 +58,20 @@ func (v *Variable) Encode(writer *Writer) {
 
 type RGBA [4]byte
 
+// RGBAFromFloats converts [0, 1] color components, the alpha is opaque when it is not given
+func RGBAFromFloats[T float32 | float64](values []T) RGBA {
+	out := RGBA{0, 0, 0, 255}
+	for i := range min(len(values), 4) {
+		out[i] = byte(math.Round(float64(min(max(values[i], 0), 1)) * 255))
+	}
+	return out
+}
+
+// Floats returns the color components in [0, 1]
+func (r RGBA) Floats() [4]float32 {
+	return [4]float32{float32(r[0]) / 255, float32(r[1]) / 255, float32(r[2]) / 255, float32(r[3]) / 255}
+}
+
 func (r *RGBA) Decode(reader *Reader) (err error) {
 	for i := range 4 {
 		if r[i], err = reader.ReadU8(); err != nil {
@@ -86,15 +102,15 @@ func (m *Material) Decode(reader *Reader) (err error) {
 	}
 
 	if err = m.Variables.Ambient.Decode(reader); err != nil {
-		return
+		return withField(err, "Variables.Ambient")
 	}
 
 	if err = m.Variables.Diffuse.Decode(reader); err != nil {
-		return
+		return withField(err, "Variables.Diffuse")
 	}
 
 	if err = m.Variables.Specular.Decode(reader); err != nil {
-		return
+		return withField(err, "Variables.Specular")
 	}
 
 	if m.Variables.StencilTex2D, err = reader.ReadUTF(); err != nil {
@@ -106,11 +122,11 @@ func (m *Material) Decode(reader *Reader) (err error) {
 	}
 
 	if err = m.Variables.Colorize.Decode(reader); err != nil {
-		return
+		return withField(err, "Variables.Colorize")
 	}
 
 	if err = m.Variables.Emission.Decode(reader); err != nil {
-		return
+		return withField(err, "Variables.Emission")
 	}
 
 	if m.Variables.OpacityTex2D, err = reader.ReadUTF(); err != nil {
@@ -146,7 +162,7 @@ func (m *Material) Decode(reader *Reader) (err error) {
 	if m.ShaderConfig&0x8000 != 0 {
 		for i := range 4 {
 			if m.StencilScaleOffset[i], err = reader.ReadFloat(); err != nil {
-				return err
+				return withField(err, "StencilScaleOffset[%d]", i)
 			}
 		}
 	}
@@ -0,0 +1,210 @@
+package scw
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// LatestVersion is the newest scw version Migrate can convert to
+const LatestVersion = 2
+
+var (
+	// ErrUnsupportedVersion the version is not between 0 and LatestVersion
+	ErrUnsupportedVersion = errors.New("unsupported scw version")
+)
+
+type ChangeKind string
+
+const (
+	// Synthesized the field does not exist in the source version, a default was set
+	Synthesized ChangeKind = "synthesized"
+	// Dropped the field does not exist in the target version, its value is lost
+	Dropped ChangeKind = "dropped"
+	// Renamed the value is named differently in the target version
+	Renamed ChangeKind = "renamed"
+	// Converted the value is stored differently in the target version
+	Converted ChangeKind = "converted"
+)
+
+// Change is a field changed by Migrate, Field is a path like the one of DecodeError
+type Change struct {
+	Kind   ChangeKind
+	Field  string
+	Detail string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s: %s", c.Kind, c.Field, c.Detail)
+}
+
+// MigrationReport lists the changes done by Migrate
+type MigrationReport struct {
+	FromVersion, ToVersion uint16
+	FromMinor, ToMinor     int
+	Changes                []Change
+}
+
+func (r *MigrationReport) add(kind ChangeKind, field, format string, args ...any) {
+	r.Changes = append(r.Changes, Change{Kind: kind, Field: field, Detail: fmt.Sprintf(format, args...)})
+}
+
+// migrationStep converts a File to the next (upgrade) or previous (downgrade) version
+type migrationStep func(f *File, report *MigrationReport)
+
+var (
+	// upgrades[v] migrates a File from version v to v + 1
+	upgrades = [LatestVersion]migrationStep{upgradeV0, upgradeV1}
+	// downgrades[v] migrates a File from version v + 1 to v
+	downgrades = [LatestVersion]migrationStep{downgradeV1, downgradeV2}
+)
+
+// Migrate converts file to targetVersion one version at a time, targetMinor is the minor version
+// of v0 targets, MinorVersionAuto keeps the one of the file (5 if it was not a v0 file)
+func Migrate(file *File, targetVersion uint16, targetMinor int) (*MigrationReport, error) {
+	if file.Version > LatestVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, file.Version)
+	}
+
+	if targetVersion > LatestVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, targetVersion)
+	}
+
+	if targetMinor < MinorVersionAuto {
+		return nil, fmt.Errorf("invalid scw minor version: %d", targetMinor)
+	}
+
+	report := &MigrationReport{FromVersion: file.Version, FromMinor: file.MinorVersion}
+
+	for file.Version < targetVersion {
+		upgrades[file.Version](file, report)
+		file.Version++
+	}
+
+	for file.Version > targetVersion {
+		downgrades[file.Version-1](file, report)
+		file.Version--
+	}
+
+	if file.Version == 0 && targetMinor != MinorVersionAuto {
+		setMinorVersion(file, targetMinor, report)
+	}
+
+	report.ToVersion, report.ToMinor = file.Version, file.MinorVersion
+	return report, nil
+}
+
+// setMinorVersion changes the minor version of a v0 File, skin weights are resized if needed
+func setMinorVersion(f *File, minor int, report *MigrationReport) {
+	resizeWeights(f, f.MinorVersion, minor, report)
+
+	if f.MinorVersion != minor {
+		report.add(Synthesized, "MinorVersion", "set to %d", minor)
+	}
+	f.MinorVersion = minor
+}
+
+// resizeWeights converts the skin weights of a v0 File between byte and 16 bits weights
+func resizeWeights(f *File, fromMinor, toMinor int, report *MigrationReport) {
+	from, to := ByteWeights(0, fromMinor), ByteWeights(0, toMinor)
+	if from == to {
+		return
+	}
+
+	for i, geom := range f.Geometries {
+		if len(geom.SkinWeights) == 0 {
+			continue
+		}
+
+		for j := range geom.SkinWeights {
+			weights := &geom.SkinWeights[j].Weights
+			for k := range weights {
+				if to {
+					weights[k] = uint16(math.Round(float64(weights[k]) / 257))
+				} else {
+					weights[k] *= 257 // 255 -> 65535
+				}
+			}
+		}
+
+		if to {
+			report.add(Converted, fmt.Sprintf("GEOM[%d].SkinWeights", i), "narrowed to bytes, precision is lost")
+		} else {
+			report.add(Converted, fmt.Sprintf("GEOM[%d].SkinWeights", i), "widened to 16 bits")
+		}
+	}
+}
+
+// renameVertices renames the position sources of every geometry
+func renameVertices(f *File, from, to string, report *MigrationReport) {
+	for i, geom := range f.Geometries {
+		for j := range geom.Vertices {
+			if geom.Vertices[j].Name == from {
+				geom.Vertices[j].Name = to
+				report.add(Renamed, fmt.Sprintf("GEOM[%d].Vertices[%d].Name", i, j), "%s to %s", from, to)
+			}
+		}
+	}
+}
+
+// upgradeV0 only v0 files have a minor version, weights are stored like minor version 5 ones
+func upgradeV0(f *File, report *MigrationReport) {
+	resizeWeights(f, f.MinorVersion, 5, report)
+
+	if f.MinorVersion != MinorVersionAuto {
+		report.add(Dropped, "MinorVersion", "%d, v1 files have none", f.MinorVersion)
+	}
+	f.MinorVersion = 0
+}
+
+func downgradeV1(f *File, report *MigrationReport) {
+	f.MinorVersion = 5
+	report.add(Synthesized, "MinorVersion", "set to 5")
+}
+
+// upgradeV1 v2 files have a byte at the end of the header and material Unk2, they have no
+// geometry IgnoredMatrix and their positions are named POSITION instead of VERTEX
+func upgradeV1(f *File, report *MigrationReport) {
+	if f.Unknown == -1 {
+		f.Unknown = 0
+		report.add(Synthesized, "HEAD.Unknown", "set to 0")
+	}
+
+	identity := Identity()
+	for i, geom := range f.Geometries {
+		if geom.IgnoredMatrix != identity && geom.IgnoredMatrix != (Matrix4x4{}) {
+			report.add(Dropped, fmt.Sprintf("GEOM[%d].IgnoredMatrix", i), "%v", geom.IgnoredMatrix)
+		}
+		geom.IgnoredMatrix = Matrix4x4{}
+	}
+
+	renameVertices(f, "VERTEX", "POSITION", report)
+
+	for i, mat := range f.Materials {
+		mat.Variables.Unk2 = ""
+		report.add(Synthesized, fmt.Sprintf("MATE[%d].Variables.Unk2", i), "set to an empty string")
+	}
+}
+
+func downgradeV2(f *File, report *MigrationReport) {
+	if f.Unknown != -1 {
+		if f.Unknown != 0 {
+			report.add(Dropped, "HEAD.Unknown", "%d", f.Unknown)
+		}
+		f.Unknown = -1
+	}
+
+	for i, geom := range f.Geometries {
+		geom.IgnoredMatrix = Identity()
+		report.add(Synthesized, fmt.Sprintf("GEOM[%d].IgnoredMatrix", i), "set to identity")
+	}
+
+	renameVertices(f, "POSITION", "VERTEX", report)
+
+	for i, mat := range f.Materials {
+		if len(mat.Variables.Unk2) != 0 {
+			report.add(Dropped, fmt.Sprintf("MATE[%d].Variables.Unk2", i), "%q", mat.Variables.Unk2)
+		}
+		mat.Variables.Unk2 = ""
+	}
+}
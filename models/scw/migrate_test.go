@@ -0,0 +1,118 @@
+package scw
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func encode(t *testing.T, file *File) []byte {
+	t.Helper()
+
+	data, err := file.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMigrateRoundTrip(t *testing.T) {
+	files := corpus(t)
+
+	for _, test := range []struct {
+		name    string
+		through uint16
+	}{
+		// the v0 fixtures have the header byte v1 files do not have, v1 keeps it, not v2 migrated back to v1
+		{"v0_minor0.scw", 1},
+		{"v0_minor5.scw", 1},
+		{"v1.scw", 2},
+		{"v1.scw", 0},
+		{"v2.scw", 0},
+	} {
+		data := files[test.name]
+		file := load(t, data, LoadOptions{})
+		version, minor := file.Version, file.MinorVersion
+
+		if _, err := Migrate(file, test.through, MinorVersionAuto); err != nil {
+			t.Fatal(err)
+		}
+
+		// the migrated file is valid for its version
+		migrated := load(t, encode(t, file), LoadOptions{})
+		if migrated.Version != test.through {
+			t.Fatalf("%s: migrated to version %d, expected %d", test.name, migrated.Version, test.through)
+		}
+
+		if _, err := Migrate(migrated, version, minor); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encode(t, migrated), data) {
+			t.Fatalf("%s migrated to v%d and back differs from the original", test.name, test.through)
+		}
+	}
+}
+
+func TestMigrateReport(t *testing.T) {
+	file := load(t, corpus(t)["v1.scw"], LoadOptions{})
+
+	report, err := Migrate(file, 2, MinorVersionAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.FromVersion != 1 || report.ToVersion != 2 {
+		t.Fatalf("report from v%d to v%d, expected v1 to v2", report.FromVersion, report.ToVersion)
+	}
+
+	expected := []Change{{Synthesized, "HEAD.Unknown", "set to 0"}}
+	for i, geometry := range file.Geometries {
+		for j := range geometry.Vertices {
+			if geometry.Vertices[j].Name == "POSITION" {
+				expected = append(expected, Change{Renamed, fmt.Sprintf("GEOM[%d].Vertices[%d].Name", i, j), "VERTEX to POSITION"})
+			}
+		}
+	}
+	for _, change := range expected {
+		if !slices.Contains(report.Changes, change) {
+			t.Fatalf("report %v does not have %v", report.Changes, change)
+		}
+	}
+}
+
+func TestMigrateInvalid(t *testing.T) {
+	file := load(t, corpus(t)["v2.scw"], LoadOptions{})
+
+	if _, err := Migrate(file, LatestVersion+1, MinorVersionAuto); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("Migrate returned %v, expected ErrUnsupportedVersion", err)
+	}
+
+	if _, err := Migrate(file, 0, MinorVersionAuto-1); err == nil {
+		t.Fatal("Migrate accepted an invalid minor version")
+	}
+
+	if file.Version != 2 {
+		t.Fatalf("failed migrations changed the version to %d", file.Version)
+	}
+}
+
+func TestMigrateWeights(t *testing.T) {
+	file := load(t, corpus(t)["v0_minor0.scw"], LoadOptions{})
+	weights := slices.Clone(file.Geometries[0].SkinWeights)
+
+	if _, err := Migrate(file, 2, MinorVersionAuto); err != nil {
+		t.Fatal(err)
+	}
+
+	// byte weights are widened to 16 bits, 255 being 65535
+	migrated := load(t, encode(t, file), LoadOptions{})
+	for i, weight := range migrated.Geometries[0].SkinWeights {
+		for j, w := range weight.Weights {
+			if w != weights[i].Weights[j]*257 {
+				t.Fatalf("SkinWeights[%d].Weights[%d] = %d, expected %d", i, j, w, weights[i].Weights[j]*257)
+			}
+		}
+	}
+}
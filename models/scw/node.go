@@ -62,22 +62,22 @@ func (f *KeyFrame) Decode(reader *Reader, u8 uint8, v58 uint16, Frames []KeyFram
 		if val, err = reader.ReadI16(); err != nil {
 			return
 		}
-		f.Rotation.X = float32(val) * 0.000030758
+		f.Rotation.X = float32(val) * rotationScale
 
 		if val, err = reader.ReadI16(); err != nil {
 			return
 		}
-		f.Rotation.Y = float32(val) * 0.000030758
+		f.Rotation.Y = float32(val) * rotationScale
 
 		if val, err = reader.ReadI16(); err != nil {
 			return
 		}
-		f.Rotation.Z = float32(val) * 0.000030758
+		f.Rotation.Z = float32(val) * rotationScale
 
 		if val, err = reader.ReadI16(); err != nil {
 			return
 		}
-		f.Rotation.W = float32(val) * 0.000030758
+		f.Rotation.W = float32(val) * rotationScale
 	} else {
 		f.Rotation = Frames[0].Rotation
 	}
@@ -133,6 +133,14 @@ func (f *KeyFrame) Decode(reader *Reader, u8 uint8, v58 uint16, Frames []KeyFram
 	return
 }
 
+// rotationScale is the scale of the int16 samples of key frame rotations
+const rotationScale = 0.000030758
+
+// rotationSample quantizes a rotation component, it is rounded so decoded samples are encoded back as they were
+func rotationSample(v float32) int16 {
+	return int16(min(max(math.Round(float64(v/rotationScale)), math.MinInt16), math.MaxInt16))
+}
+
 func (f *KeyFrame) Encode(writer *Writer, u8 uint8, v58 uint16, Frames []KeyFrame) {
 	writer.WriteU16(f.ID)
 
@@ -143,10 +151,10 @@ func (f *KeyFrame) Encode(writer *Writer, u8 uint8, v58 uint16, Frames []KeyFram
 		v61 = -1
 	}
 	if v58 == 0 || (v61&1) != 0 {
-		writer.WriteI16(int16(f.Rotation.X / 0.000030758))
-		writer.WriteI16(int16(f.Rotation.Y / 0.000030758))
-		writer.WriteI16(int16(f.Rotation.Z / 0.000030758))
-		writer.WriteI16(int16(f.Rotation.W / 0.000030758))
+		writer.WriteI16(rotationSample(f.Rotation.X))
+		writer.WriteI16(rotationSample(f.Rotation.Y))
+		writer.WriteI16(rotationSample(f.Rotation.Z))
+		writer.WriteI16(rotationSample(f.Rotation.W))
 	}
 
 	if v58 == 0 || (v61&2) != 0 {
@@ -219,22 +227,30 @@ func (n *NodeInstance) Decode(reader *Reader) (err error) {
 		n.Materials = make([]InstanceMaterial, count)
 		for i := range count {
 			if err = n.Materials[i].Decode(reader); err != nil {
-				return
+				return withField(err, "Materials[%d]", i)
 			}
 		}
-	case "LIGH":
-		panic("LIGH is not supported yet")
 	case "CAME":
 		if n.CameraTarget, err = reader.ReadUTF(); err != nil {
 			return
 		}
+	case "LIGH":
+		// the payload after the Target is not known, so neither is where the next instance starts
+		return ErrLightInstance
 	default:
-		return fmt.Errorf("invalid or unsupported instance material type: %s", n.Type)
+		return fmt.Errorf("%w: %s", ErrUnsupportedInstance, n.Type)
 	}
 	return
 }
 
 func (n *NodeInstance) Encode(writer *Writer) {
+	switch n.Type {
+	case "GEOM", "CONT", "CAME":
+	default:
+		writer.Fail(fmt.Errorf("%w: %s", ErrUnsupportedInstance, n.Type))
+		return
+	}
+
 	writer.WriteStringChars(n.Type)
 
 	writer.WriteStringUTF(n.Target)
@@ -245,12 +261,8 @@ func (n *NodeInstance) Encode(writer *Writer) {
 		for _, mat := range n.Materials {
 			mat.Encode(writer)
 		}
-	case "LIGH":
-		panic("LIGH is not supported yet")
 	case "CAME":
 		writer.WriteStringUTF(n.CameraTarget)
-	default:
-		panic(fmt.Errorf("invalid or unsupported instance material type: %s", n.Type))
 	}
 }
 
@@ -274,7 +286,7 @@ func (n *Node) Decode(reader *Reader) (err error) {
 
 	for i := range count {
 		if err = n.Instances[i].Decode(reader); err != nil {
-			return
+			return withField(err, "Instances[%d]", i)
 		}
 	}
 
@@ -294,7 +306,7 @@ func (n *Node) Decode(reader *Reader) (err error) {
 
 		for i := range count {
 			if err = n.Frames[i].Decode(reader, u8, i, n.Frames); err != nil {
-				return
+				return withField(err, "Frames[%d]", i)
 			}
 		}
 	}
@@ -302,8 +314,14 @@ func (n *Node) Decode(reader *Reader) (err error) {
 	return
 }
 
+// computeFrameFlags returns the flags KeyFrame.Decode reads: a bit is set for every property that
+// changes (1 rotation, 2/4/8 translation x/y/z, 0x10/0x20/0x40 scale x/y/z), the properties
+// without a bit are only stored in the first frame and copied to the others
 func computeFrameFlags(Frames []KeyFrame) byte {
 	flags := byte(0)
+	if len(Frames) < 2 {
+		return flags
+	}
 
 	// check if all frames matches the properties (rotation, ...)  of the first frame
 	rotation := true
@@ -346,31 +364,31 @@ func computeFrameFlags(Frames []KeyFrame) byte {
 		}
 	}
 
-	if rotation {
+	if !rotation {
 		flags |= 1 << 0
 	}
 
-	if translationX {
+	if !translationX {
 		flags |= 1 << 1
 	}
 
-	if translationY {
+	if !translationY {
 		flags |= 1 << 2
 	}
 
-	if translationZ {
+	if !translationZ {
 		flags |= 1 << 3
 	}
 
-	if scaleX {
+	if !scaleX {
 		flags |= 1 << 4
 	}
 
-	if scaleY {
+	if !scaleY {
 		flags |= 1 << 5
 	}
 
-	if scaleZ {
+	if !scaleZ {
 		flags |= 1 << 6
 	}
 
@@ -390,10 +408,10 @@ func (n *Node) Encode(writer *Writer) {
 	writer.WriteU16(uint16(len(n.Frames)))
 
 	if len(n.Frames) > 0 {
-		n.FramesFlags = 0
-
-		if len(n.Frames) > 1 {
-			n.FramesFlags = computeFrameFlags(n.Frames)
+		// the loaded flags are kept while they flag every changing property (storing more
+		// than needed is valid) so unmodified files are encoded as they were
+		if flags := computeFrameFlags(n.Frames); flags&^n.FramesFlags != 0 {
+			n.FramesFlags = flags
 		}
 
 		writer.WriteU8(n.FramesFlags)
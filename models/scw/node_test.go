@@ -0,0 +1,161 @@
+package scw
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+// frameFlagsNode is a node body with 2 frames where only the X translation changes, flags are
+// the frame flags and second the values stored in the second frame (only the X translation is needed)
+func frameFlagsNode(flags byte, second ...float32) []byte {
+	writer := NewWriter()
+	writer.WriteStringUTF("node")
+	writer.WriteStringUTF("")
+	writer.WriteU16(0) // instances
+
+	writer.WriteU16(2) // frames
+	writer.WriteU8(flags)
+
+	// the first frame stores every value, -32738 was not encoded back as it was when samples were truncated
+	writer.WriteU16(0)
+	for _, v := range []int16{-32738, 0, 0, 32512} {
+		writer.WriteI16(v)
+	}
+	for _, v := range []float32{1, 2, 3, 1, 1, 1} {
+		writer.WriteFloat(v)
+	}
+
+	writer.WriteU16(1)
+	for _, v := range second {
+		writer.WriteFloat(v)
+	}
+
+	return writer.Bytes()
+}
+
+func TestFrameFlagsRoundTrip(t *testing.T) {
+	data := frameFlagsNode(1<<1, 5)
+
+	var node Node
+	if err := node.Decode(NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	second := node.Frames[1]
+	if second.Translation != (Vector3{5, 2, 3}) || second.Scale != (Vector3{1, 1, 1}) || second.Rotation != node.Frames[0].Rotation {
+		t.Fatalf("second frame %+v does not copy the unflagged values of the first one", second)
+	}
+
+	writer := NewWriter()
+	node.Encode(writer)
+	if !bytes.Equal(writer.Bytes(), data) {
+		t.Fatalf("encoded node differs from the decoded one:\n%x\n%x", writer.Bytes(), data)
+	}
+
+	if node.FramesFlags != 1<<1 {
+		t.Fatalf("frame flags %07b, expected %07b", node.FramesFlags, 1<<1)
+	}
+}
+
+func TestComputeFrameFlags(t *testing.T) {
+	first := KeyFrame{Scale: Vector3{1, 1, 1}}
+
+	for _, test := range []struct {
+		name   string
+		modify func(frame *KeyFrame)
+		flags  byte
+	}{
+		{"static", func(frame *KeyFrame) {}, 0},
+		{"rotation", func(frame *KeyFrame) { frame.Rotation.W = 1 }, 1 << 0},
+		{"translation", func(frame *KeyFrame) { frame.Translation = Vector3{1, 2, 3} }, 1<<1 | 1<<2 | 1<<3},
+		{"scale y", func(frame *KeyFrame) { frame.Scale.Y = 2 }, 1 << 5},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			frame := first
+			test.modify(&frame)
+
+			if flags := computeFrameFlags([]KeyFrame{first, frame}); flags != test.flags {
+				t.Fatalf("flags %07b, expected %07b", flags, test.flags)
+			}
+		})
+	}
+}
+
+func TestRotationSamples(t *testing.T) {
+	for sample := math.MinInt16; sample <= math.MaxInt16; sample++ {
+		v := float32(int16(sample)) * rotationScale
+		if encoded := rotationSample(v); encoded != int16(sample) {
+			t.Fatalf("sample %d decoded to %g is encoded back as %d", sample, v, encoded)
+		}
+	}
+}
+
+func TestFrameFlagsKept(t *testing.T) {
+	// the Y translation is also stored in the second frame even though it does not change
+	data := frameFlagsNode(1<<1|1<<2, 5, 2)
+
+	var node Node
+	if err := node.Decode(NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	writer := NewWriter()
+	node.Encode(writer)
+	if !bytes.Equal(writer.Bytes(), data) {
+		t.Fatalf("encoded node differs from the decoded one:\n%x\n%x", writer.Bytes(), data)
+	}
+}
+
+func TestLightInstanceKept(t *testing.T) {
+	data := corpus(t)["lights.scw"]
+	file := load(t, data, LoadOptions{})
+
+	// the nodes before the one with a light instance are decoded, the rest of the chunk is kept
+	var names []string
+	for _, node := range file.Nodes {
+		names = append(names, node.Name)
+	}
+	if !slices.Equal(names, []string{"root", "bone", "mesh", "camnode"}) || file.RawNodes == nil || file.RawNodes.Count != 1 {
+		t.Fatalf("decoded nodes %v and raw nodes %+v, expected 4 nodes and 1 raw node", names, file.RawNodes)
+	}
+
+	var tags []string
+	for _, chunk := range file.UnknownChunks {
+		tags = append(tags, chunk.Tag)
+	}
+	if slices.Contains(tags, "NODE") || !slices.Contains(tags, "LIGH") {
+		t.Fatalf("unknown chunks %v, expected only the LIGH chunks", tags)
+	}
+
+	if encoded := encode(t, file); !bytes.Equal(encoded, data) {
+		t.Fatal("file with raw nodes is not encoded back as it was")
+	}
+
+	// a new light instance can not be encoded either
+	writer := NewWriter()
+	instance := NodeInstance{Type: "LIGH", Target: "light"}
+	if instance.Encode(writer); !errors.Is(writer.Err(), ErrUnsupportedInstance) {
+		t.Fatalf("Encode failed with %v, expected ErrUnsupportedInstance", writer.Err())
+	}
+}
+
+func TestLightInstanceValidate(t *testing.T) {
+	file := load(t, corpus(t)["lights.scw"], LoadOptions{})
+	if errs := file.Validate(); len(errs) != 0 {
+		t.Fatalf("valid file with a light instance reported %v", errs)
+	}
+
+	// a joint that is not decoded may be one of the raw nodes
+	file.Geometries[0].Skins.Joints[1] = "raw"
+	if errs := file.Validate(); len(errs) != 0 {
+		t.Fatalf("joint that may be a raw node reported %v", errs)
+	}
+
+	file.RawNodes = nil
+	if errs := file.Validate(); len(errs) != 1 {
+		t.Fatalf("unknown joint reported %v, expected 1 error", errs)
+	}
+}
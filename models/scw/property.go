@@ -7,10 +7,18 @@ type Sc3dProperty interface {
 	Encode(writer *Writer)
 }
 
-func EncodeSc3dProperty(prop Sc3dProperty, writer *Writer) {
+// EncodeSc3dProperty writes prop as a chunk, only its body is buffered since the chunk starts with its length
+//
+// errors set by prop with Writer.Fail are returned as an *EncodeError and nothing is written
+func EncodeSc3dProperty(prop Sc3dProperty, writer *Writer) error {
 	w := NewWriter()
 	w.WriteStringChars(prop.Tag())
 	prop.Encode(w)
+	if err := w.Err(); err != nil {
+		err = &EncodeError{Chunk: prop.Tag(), Err: err}
+		writer.Fail(err)
+		return err
+	}
 	bytes := w.Bytes()
 
 	writer.WriteU32(uint32(len(bytes) - len(prop.Tag())))
@@ -18,4 +26,6 @@ func EncodeSc3dProperty(prop Sc3dProperty, writer *Writer) {
 
 	checksum := crc32.ChecksumIEEE(bytes)
 	writer.WriteU32(checksum)
+
+	return writer.Err()
 }
@@ -0,0 +1,115 @@
+package scw
+
+import (
+	"fmt"
+	"math"
+)
+
+// QuantizationError a value of a SourceArray does not fit in an int16 sample with its scale
+type QuantizationError struct {
+	Source string // name of the SourceArray
+	Index  int    // index of the value in Data
+	Value  float64
+	Scale  float32
+}
+
+func (e *QuantizationError) Error() string {
+	return fmt.Sprintf("%s Data[%d] = %g overflows the int16 samples of scale %g (max %g), use a bigger scale or 0 to compute it",
+		e.Source, e.Index, e.Value, e.Scale, float64(e.Scale)*math.MaxInt16)
+}
+
+// EncodeScale returns the scale Encode uses: Scale, or the one computed by QuantizationScale when it is 0
+func (s *SourceArray) EncodeScale() float32 {
+	if s.Scale != 0 {
+		return s.Scale
+	}
+
+	for _, v := range s.Data {
+		if v != 0 {
+			return QuantizationScale(s.Data)
+		}
+	}
+
+	// only zeros, kept as is
+	return 0
+}
+
+// Quantize returns the int16 samples of Data with scale, the values that did not change since the
+// array was decoded use their Raw sample, the others are rounded
+//
+// a *QuantizationError is returned for the first value that does not fit
+func (s *SourceArray) Quantize(scale float32) ([]int16, error) {
+	samples := make([]int16, len(s.Data))
+
+	for i, v := range s.Data {
+		if i < len(s.Raw) && scale == s.Scale && float64(s.Raw[i])*float64(scale) == v {
+			samples[i] = s.Raw[i]
+			continue
+		}
+
+		if v == 0 {
+			continue
+		}
+
+		// rounded, a truncated value can be off by one because of floating point errors
+		sample := math.Round(v / float64(scale))
+		if math.IsNaN(sample) || sample < math.MinInt16 || sample > math.MaxInt16 {
+			return nil, &QuantizationError{Source: s.Name, Index: i, Value: v, Scale: scale}
+		}
+		samples[i] = int16(sample)
+	}
+
+	return samples, nil
+}
+
+// Requantize sets Scale to the smallest one that fits Data, the raw samples are dropped
+func (s *SourceArray) Requantize() {
+	s.Scale = QuantizationScale(s.Data)
+	s.Raw = nil
+}
+
+// QuantizationStats is the result of encoding a SourceArray with its scale
+type QuantizationStats struct {
+	Geometry string
+	Source   string
+	Scale    float32 // the scale written by Encode
+	// MaxError is the biggest difference between a value and its encoded sample
+	MaxError float64
+	// Err is a *QuantizationError if a value overflows
+	Err error
+}
+
+// QuantizationReport encodes the samples of every SourceArray of the File without writing them
+func (f *File) QuantizationReport() []QuantizationStats {
+	var report []QuantizationStats
+
+	for _, geom := range f.Geometries {
+		for i := range geom.Vertices {
+			source := &geom.Vertices[i]
+
+			stats := QuantizationStats{Geometry: geom.Name, Source: source.Name, Scale: source.EncodeScale()}
+
+			samples, err := source.Quantize(stats.Scale)
+			if err != nil {
+				stats.Err = err
+			} else {
+				for j, sample := range samples {
+					stats.MaxError = max(stats.MaxError, math.Abs(source.Data[j]-float64(sample)*float64(stats.Scale)))
+				}
+			}
+
+			report = append(report, stats)
+		}
+	}
+
+	return report
+}
+
+// Requantize computes the scale of every SourceArray of the File, see SourceArray.Requantize
+func (f *File) Requantize() {
+	for _, geom := range f.Geometries {
+		for i := range geom.Vertices {
+			geom.Vertices[i].Requantize()
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package scw
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestQuantizationScale(t *testing.T) {
+	data := []float64{0.5, -2, 1}
+	scale := QuantizationScale(data)
+
+	samples, err := (&SourceArray{Name: "POSITION", Stride: 3, Scale: scale, Data: data}).Quantize(scale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if samples[1] != -math.MaxInt16 {
+		t.Fatalf("the biggest magnitude is quantized to %d, expected %d", samples[1], -math.MaxInt16)
+	}
+
+	if scale := QuantizationScale([]float64{0, 0}); scale != 1 {
+		t.Fatalf("scale of zeros %g, expected 1", scale)
+	}
+}
+
+func TestEncodeScale(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		source SourceArray
+		scale  float32
+	}{
+		{"set", SourceArray{Scale: 0.5, Data: []float64{100}}, 0.5},
+		{"computed", SourceArray{Data: []float64{math.MaxInt16}}, 1},
+		{"zeros", SourceArray{Data: []float64{0, 0}}, 0},
+	} {
+		if scale := test.source.EncodeScale(); scale != test.scale {
+			t.Fatalf("%s: scale %g, expected %g", test.name, scale, test.scale)
+		}
+	}
+}
+
+func TestQuantizeOverflow(t *testing.T) {
+	file := load(t, corpus(t)["v2.scw"], LoadOptions{})
+	source := &file.Geometries[0].Vertices[0]
+	source.Data[1] = float64(source.EncodeScale()) * (math.MaxInt16 + 1)
+
+	var quantizationErr *QuantizationError
+	if _, err := file.Encode(); !errors.As(err, &quantizationErr) {
+		t.Fatalf("Encode returned %v, expected a *QuantizationError", err)
+	}
+	if quantizationErr.Source != source.Name || quantizationErr.Index != 1 {
+		t.Fatalf("got %v, expected Data[1] of %s", quantizationErr, source.Name)
+	}
+
+	report := file.QuantizationReport()
+	if !errors.As(report[0].Err, &quantizationErr) {
+		t.Fatalf("report %+v has no *QuantizationError", report[0])
+	}
+
+	// a computed scale fits every value
+	file.Requantize()
+	if source.Raw != nil {
+		t.Fatal("raw samples kept by Requantize")
+	}
+	if _, err := file.Encode(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuantizationReport(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	data := make([]float64, 300)
+	for i := range data {
+		data[i] = random.NormFloat64() * 100
+	}
+
+	file := &File{Geometries: []*Geometry{{Name: "geometry", Vertices: []SourceArray{{Name: "POSITION", Stride: 3, Data: data}}}}}
+
+	report := file.QuantizationReport()
+	if len(report) != 1 || report[0].Err != nil {
+		t.Fatalf("report %+v", report)
+	}
+
+	// rounded samples are at most half a step off
+	if stats := report[0]; stats.MaxError == 0 || stats.MaxError > float64(stats.Scale)/2 {
+		t.Fatalf("max error %g for a scale of %g", stats.MaxError, stats.Scale)
+	}
+}
@@ -10,6 +10,8 @@ type Reader struct {
 	data      []byte
 	offset    int
 	SkipBytes int
+	// KeepRawSamples makes SourceArray.Decode keep the int16 samples in Raw
+	KeepRawSamples bool
 }
 
 func NewReader(data []byte) *Reader {
@@ -23,6 +25,15 @@ func (r *Reader) hasData(n int) bool {
 	return r.offset+n <= len(r.data)
 }
 
+// Ensure checks that count values of size bytes are left before they are allocated,
+// so a corrupted count can not allocate more than the data holds
+func (r *Reader) Ensure(count uint64, size int) error {
+	if count*uint64(size) > uint64(len(r.data)-r.offset) {
+		return io.ErrShortBuffer
+	}
+	return nil
+}
+
 func (r *Reader) Read(n int) ([]byte, error) {
 	r.SkipBytes -= n
 	if !r.hasData(n) {
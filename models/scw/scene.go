@@ -2,6 +2,15 @@ package scw
 
 type Scene struct {
 	Nodes []Node
+	// RawNodes are the nodes from the first one with a LIGH instance on, the layout of light instances
+	// is not known so neither is where the nodes after one start
+	RawNodes *RawNodes `json:",omitempty"`
+}
+
+// RawNodes is the end of a NODE chunk that was not decoded, it is written back as is after Scene.Nodes
+type RawNodes struct {
+	Count uint16 // number of nodes in Data
+	Data  []byte // base64 in JSON
 }
 
 func (s *Scene) Tag() string {
@@ -9,8 +18,17 @@ func (s *Scene) Tag() string {
 }
 
 func (s *Scene) Encode(writer *Writer) {
-	writer.WriteU16(uint16(len(s.Nodes)))
+	count := len(s.Nodes)
+	if s.RawNodes != nil {
+		count += int(s.RawNodes.Count)
+	}
+
+	writer.WriteU16(uint16(count))
 	for _, node := range s.Nodes {
 		node.Encode(writer)
 	}
+
+	if s.RawNodes != nil {
+		writer.WriteBytes(s.RawNodes.Data)
+	}
 }
@@ -1,11 +1,20 @@
 package scw
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
+	"slices"
+	"strconv"
 )
 
+// Magic is the first 4 bytes of every scw File
+const Magic = "SC3D"
+
+// MinorVersionAuto detects the minor version of v0 files from their data, see File.MinorVersion
+const MinorVersionAuto = -1
+
 var (
 	// ErrInvalidSCWMagic expected magic is to be SC3D (first 4 bytes of the File)
 	ErrInvalidSCWMagic = errors.New("invalid scw file magic")
@@ -17,13 +26,45 @@ type File struct {
 	Materials  []*Material
 	Geometries []*Geometry
 	Scene
-	Cameras      []*Camera3D
-	MinorVersion int // used for versions under 2
+	Cameras []*Camera3D
+	// LIGH chunks are kept in UnknownChunks as the layout of lights is not known, see Scene.RawNodes
+	// for the nodes with light instances
+	// MinorVersion of v0 files, it changes the size of skin weights (see ByteWeights),
+	// files loaded with MinorVersionAuto get the one detected from their geometries
+	MinorVersion int
+	// UnknownChunks are written back at their position by Encode
+	UnknownChunks []UnknownChunk `json:",omitempty"`
+	// ChunkOrder is the tags of the chunks in the order they were loaded, Encode writes them back
+	// in this order so unmodified files are encoded as they were
+	ChunkOrder []string `json:",omitempty"`
+}
+
+// IsSCW reports if data starts with the scw magic
+func IsSCW(data []byte) bool {
+	return len(data) >= len(Magic) && string(data[:len(Magic)]) == Magic
 }
 
+// UniqueName returns name (or fallback if it is empty) suffixed as needed to not collide with used ones,
+// importers use it as scw chunks reference each other by name
+func UniqueName(name, fallback string, used map[string]bool) string {
+	if len(name) == 0 {
+		name = fallback
+	}
+
+	unique := name
+	for i := 1; used[unique]; i++ {
+		unique = name + "_" + strconv.Itoa(i)
+	}
+
+	used[unique] = true
+	return unique
+}
+
+// New returns a File to load from data, its minor version is detected unless it is set before loading
 func New(data []byte) *File {
 	return &File{
-		reader: NewReader(data),
+		reader:       NewReader(data),
+		MinorVersion: MinorVersionAuto,
 	}
 }
 
@@ -47,121 +88,161 @@ func (f *File) LoadJSON() (err error) {
 	return nil
 }
 
-// Load loads a File, for now support of scw version 2 is under development
-//
-// in the future support for version 1 is planned.
-func (f *File) Load() (err error) {
-	reader := f.reader
-
-	var magic string
-
-	if magic, err = reader.ReadUTFWithLength(4); err != nil {
-		return
-	} else if magic != "SC3D" {
-		return ErrInvalidSCWMagic
-	}
+// LoadOptions change how a File is loaded
+type LoadOptions struct {
+	// VerifyCRC fails the load with a *CRCError on the first chunk with a wrong checksum
+	VerifyCRC bool
+	// KeepRawSamples keeps the int16 samples of vertex data so unchanged values are encoded
+	// back bit for bit whatever the floating point errors are
+	KeepRawSamples bool
+}
 
-	var (
-		length uint32
-		prop   string
-	)
+// Load loads a File of any version (0, 1 or 2)
+func (f *File) Load() error {
+	return f.LoadWithOptions(LoadOptions{})
+}
 
-	end := false
+// LoadWithOptions loads a File like Load does
+func (f *File) LoadWithOptions(opts LoadOptions) error {
+	return f.LoadFrom(bytes.NewReader(f.reader.data), opts)
+}
 
-	for !end {
-		length, err = reader.ReadU32()
-		if err != nil {
-			return err
+// LoadFrom loads a File from r one chunk at a time, the data given to New is not used
+func (f *File) LoadFrom(r io.Reader, opts LoadOptions) (err error) {
+	decoder := NewDecoder(r)
+	decoder.file = f
+	decoder.VerifyCRC = opts.VerifyCRC
+	decoder.KeepRawSamples = opts.KeepRawSamples
+
+	for {
+		if _, err = decoder.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return
 		}
 
-		prop, err = reader.ReadUTFWithLength(4)
-		reader.SkipBytes = int(length)
-		if err != nil {
+		if _, err = decoder.Decode(); err != nil {
 			return
 		}
-		switch prop {
+	}
+
+	return nil
+}
+
+// Encode returns the encoded File, see EncodeTo
+func (f *File) Encode() ([]byte, error) {
+	writer := NewWriter()
+	f.encode(writer)
+	if err := writer.Err(); err != nil {
+		return nil, err
+	}
+	return writer.Bytes(), nil
+}
+
+// EncodeTo writes the File to w one chunk at a time, the first write error
+// or *EncodeError is returned
+func (f *File) EncodeTo(w io.Writer) error {
+	writer := NewStreamWriter(w)
+	f.encode(writer)
+	return writer.Err()
+}
+
+func (f *File) encode(writer *Writer) {
+	writer.WriteStringChars(Magic) // file magic
+
+	for _, prop := range f.chunks() {
+		EncodeSc3dProperty(prop, writer)
+	}
+}
+
+// appendUnknown appends the first unknown chunk with tag to props and removes it from unknown
+func appendUnknown(props []Sc3dProperty, unknown []*UnknownChunk, tag string) ([]Sc3dProperty, []*UnknownChunk) {
+	if i := slices.IndexFunc(unknown, func(chunk *UnknownChunk) bool { return chunk.Tag == tag }); i != -1 {
+		props = append(props, unknownProperty{unknown[i]})
+		unknown = slices.Delete(unknown, i, i+1)
+	}
+	return props, unknown
+}
+
+// chunks returns the chunks of the File in the order they are written, WEND included
+//
+// ChunkOrder is followed when it is set, chunks it does not list (added after the File was loaded)
+// are written after it in the default order: HEAD, MATE, CAME, GEOM, NODE, then WEND
+func (f *File) chunks() []Sc3dProperty {
+	materials, cameras, geometries := f.Materials, f.Cameras, f.Geometries
+	header, scene := true, true
+
+	unknown := make([]*UnknownChunk, len(f.UnknownChunks))
+	for i := range f.UnknownChunks {
+		unknown[i] = &f.UnknownChunks[i]
+	}
+	slices.SortStableFunc(unknown, func(a, b *UnknownChunk) int {
+		return a.Position - b.Position
+	})
+
+	var props []Sc3dProperty
+
+	for _, tag := range f.ChunkOrder {
+		switch tag {
 		case "HEAD":
-			if err = f.Header.Decode(reader); err != nil {
-				return
+			if header {
+				props, header = append(props, &f.Header), false
 			}
 		case "MATE":
-			material := new(Material)
-			material.SCWFile = f
-			if err = material.Decode(reader); err != nil {
-				return
-			}
-			f.Materials = append(f.Materials, material)
-		case "GEOM":
-			geometry := new(Geometry)
-			geometry.SCWFile = f
-			if err = geometry.Decode(reader); err != nil {
-				return
+			if len(materials) != 0 {
+				props, materials = append(props, materials[0]), materials[1:]
 			}
-			f.Geometries = append(f.Geometries, geometry)
 		case "CAME":
-			camera := new(Camera3D)
-			if err = camera.Decode(reader); err != nil {
-				return
+			if len(cameras) != 0 {
+				props, cameras = append(props, cameras[0]), cameras[1:]
 			}
-			f.Cameras = append(f.Cameras, camera)
-		case "NODE":
-			var nodesCount uint16
-			if nodesCount, err = reader.ReadU16(); err != nil {
-				return
+		case "GEOM":
+			if len(geometries) != 0 {
+				props, geometries = append(props, geometries[0]), geometries[1:]
 			}
-			f.Nodes = make([]Node, nodesCount)
-			for i := range nodesCount {
-				f.Nodes[i].SCWFile = f
-				if err = f.Nodes[i].Decode(reader); err != nil {
-					return
-				}
+		case "NODE":
+			if scene {
+				props, scene = append(props, &f.Scene), false
 			}
 		case "WEND":
-			reader.SkipBytes = 0
-			end = true
 		default:
-			return fmt.Errorf("unsupported scw property: %s", prop)
+			props, unknown = appendUnknown(props, unknown, tag)
 		}
-		if reader.SkipBytes != 0 {
-			panic(fmt.Sprintf("failed to parse SCW model property infully, chunk: %s, bytes left: %d", prop, reader.SkipBytes))
-		}
-
-		_, err = reader.ReadU32() // crc32(prop + buffer)
+	}
 
-		if err != nil {
-			return
+	// unknown chunks are written before the first chunk at or after their position
+	add := func(prop Sc3dProperty) {
+		for len(unknown) != 0 && unknown[0].Position <= len(props) {
+			props = append(props, unknownProperty{unknown[0]})
+			unknown = unknown[1:]
 		}
+		props = append(props, prop)
 	}
 
-	if f.Version == 2 {
-		f.MinorVersion = 0
+	if header {
+		add(&f.Header)
 	}
 
-	return
-}
-
-func (f *File) Encode() []byte {
-	writer := NewWriter()
-
-	writer.WriteStringChars("SC3D") // file magic
-
-	EncodeSc3dProperty(&f.Header, writer)
-
-	for _, mat := range f.Materials {
-		EncodeSc3dProperty(mat, writer)
+	for _, mat := range materials {
+		add(mat)
 	}
 
-	for _, cam := range f.Cameras {
-		EncodeSc3dProperty(cam, writer)
+	for _, cam := range cameras {
+		add(cam)
 	}
 
-	for _, geom := range f.Geometries {
-		EncodeSc3dProperty(geom, writer)
+	for _, geom := range geometries {
+		add(geom)
 	}
 
-	EncodeSc3dProperty(&f.Scene, writer)
+	if scene {
+		add(&f.Scene)
+	}
 
-	EncodeSc3dProperty(&Wend{}, writer)
+	// the ones after the end of the known chunks
+	for _, chunk := range unknown {
+		props = append(props, unknownProperty{chunk})
+	}
 
-	return writer.Bytes()
+	return append(props, &Wend{})
 }
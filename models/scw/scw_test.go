@@ -0,0 +1,109 @@
+package scw
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+var errWrite = errors.New("write failed")
+
+// limitedWriter fails once more than n bytes were written
+type limitedWriter struct {
+	n              int
+	writes, failed int
+}
+
+func (w *limitedWriter) Write(data []byte) (int, error) {
+	w.writes++
+	if w.failed != 0 || len(data) > w.n {
+		w.failed++
+		return 0, errWrite
+	}
+	w.n -= len(data)
+	return len(data), nil
+}
+
+func TestEncodeTo(t *testing.T) {
+	for name, data := range corpus(t) {
+		t.Run(name, func(t *testing.T) {
+			file := load(t, data, LoadOptions{})
+
+			var out bytes.Buffer
+			if err := file.EncodeTo(&out); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(out.Bytes(), data) {
+				t.Fatalf("EncodeTo wrote %d bytes differing from %s (%d bytes)", out.Len(), name, len(data))
+			}
+		})
+	}
+}
+
+func TestEncodeToWriteError(t *testing.T) {
+	data := corpus(t)["v2.scw"]
+	file := load(t, data, LoadOptions{})
+
+	for _, n := range []int{0, len(Magic), len(Magic) + 10, len(data) / 2, len(data) - 1} {
+		w := &limitedWriter{n: n}
+		if err := file.EncodeTo(w); !errors.Is(err, errWrite) {
+			t.Fatalf("%d bytes: EncodeTo returned %v, expected the write error", n, err)
+		}
+
+		// nothing is written after the first error
+		if w.failed != 1 {
+			t.Fatalf("%d bytes: %d writes failed, expected 1", n, w.failed)
+		}
+	}
+
+	// chunks are written as they are encoded, not at once
+	w := &limitedWriter{n: len(data)}
+	if err := file.EncodeTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes < len(file.ChunkOrder) {
+		t.Fatalf("%d writes for %d chunks", w.writes, len(file.ChunkOrder))
+	}
+}
+
+func chunkTags(file *File) []string {
+	var tags []string
+	for _, prop := range file.chunks() {
+		tags = append(tags, prop.Tag())
+	}
+	return tags
+}
+
+func TestChunkOrder(t *testing.T) {
+	data := corpus(t)["chunk_order.scw"]
+	file := load(t, data, LoadOptions{})
+
+	if expected := []string{"HEAD", "NODE", "GEOM", "CAME", "MATE"}; !slices.Equal(file.ChunkOrder, expected) {
+		t.Fatalf("chunk order %v, expected %v", file.ChunkOrder, expected)
+	}
+
+	// the order is kept in JSON
+	fromJSON := New(marshal(t, file))
+	if err := fromJSON.LoadJSON(); err != nil {
+		t.Fatal(err)
+	}
+	if encoded, err := fromJSON.Encode(); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(encoded, data) {
+		t.Fatal("file encoded from JSON differs from the loaded one")
+	}
+
+	// chunks added after loading follow the loaded ones
+	file.Materials = append(file.Materials, &Material{SCWFile: file, Name: "added"})
+	if tags, expected := chunkTags(file), []string{"HEAD", "NODE", "GEOM", "CAME", "MATE", "MATE", "WEND"}; !slices.Equal(tags, expected) {
+		t.Fatalf("chunks %v, expected %v", tags, expected)
+	}
+
+	// the default order without a chunk order
+	file.ChunkOrder = nil
+	if tags, expected := chunkTags(file), []string{"HEAD", "MATE", "MATE", "CAME", "GEOM", "NODE", "WEND"}; !slices.Equal(tags, expected) {
+		t.Fatalf("chunks %v, expected %v", tags, expected)
+	}
+}
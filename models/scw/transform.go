@@ -0,0 +1,104 @@
+package scw
+
+import "math"
+
+// Matrix returns the local transformation of the key frame (translation * rotation * scale)
+func (f *KeyFrame) Matrix() Matrix4x4 {
+	x, y, z, w := float64(f.Rotation.X), float64(f.Rotation.Y), float64(f.Rotation.Z), float64(f.Rotation.W)
+	if length := math.Sqrt(x*x + y*y + z*z + w*w); length > 0 {
+		x, y, z, w = x/length, y/length, z/length, w/length
+	} else {
+		w = 1
+	}
+
+	sx, sy, sz := float64(f.Scale.X), float64(f.Scale.Y), float64(f.Scale.Z)
+
+	return Matrix4x4{
+		{float32((1 - 2*(y*y+z*z)) * sx), float32(2 * (x*y - z*w) * sy), float32(2 * (x*z + y*w) * sz), f.Translation.X},
+		{float32(2 * (x*y + z*w) * sx), float32((1 - 2*(x*x+z*z)) * sy), float32(2 * (y*z - x*w) * sz), f.Translation.Y},
+		{float32(2 * (x*z - y*w) * sx), float32(2 * (y*z + x*w) * sy), float32((1 - 2*(x*x+y*y)) * sz), f.Translation.Z},
+		{0, 0, 0, 1},
+	}
+}
+
+// Decompose splits an affine transformation into the translation, rotation and scale of a KeyFrame
+//
+// shear can not be represented by key frames, so it is lost
+func (m *Matrix4x4) Decompose() (translation Vector3, rotation Quaternion, scale Vector3) {
+	translation = Vector3{X: m[0][3], Y: m[1][3], Z: m[2][3]}
+
+	var s [3]float64
+	for c := range 3 {
+		s[c] = math.Sqrt(float64(m[0][c]*m[0][c] + m[1][c]*m[1][c] + m[2][c]*m[2][c]))
+	}
+
+	// a negative determinant means the matrix mirrors, fold it into the x scale
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) - m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) + m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if det < 0 {
+		s[0] = -s[0]
+	}
+
+	scale = Vector3{X: float32(s[0]), Y: float32(s[1]), Z: float32(s[2])}
+
+	var r [3][3]float64
+	for row := range 3 {
+		for c := range 3 {
+			if s[c] != 0 {
+				r[row][c] = float64(m[row][c]) / s[c]
+			}
+		}
+	}
+
+	// reference: https://www.euclideanspace.com/maths/geometry/rotations/conversions/matrixToQuaternion/
+	var x, y, z, w float64
+
+	switch trace := r[0][0] + r[1][1] + r[2][2]; {
+	case trace > 0:
+		q := math.Sqrt(trace+1) * 2
+		w = q / 4
+		x = (r[2][1] - r[1][2]) / q
+		y = (r[0][2] - r[2][0]) / q
+		z = (r[1][0] - r[0][1]) / q
+	case r[0][0] > r[1][1] && r[0][0] > r[2][2]:
+		q := math.Sqrt(1+r[0][0]-r[1][1]-r[2][2]) * 2
+		w = (r[2][1] - r[1][2]) / q
+		x = q / 4
+		y = (r[0][1] + r[1][0]) / q
+		z = (r[0][2] + r[2][0]) / q
+	case r[1][1] > r[2][2]:
+		q := math.Sqrt(1+r[1][1]-r[0][0]-r[2][2]) * 2
+		w = (r[0][2] - r[2][0]) / q
+		x = (r[0][1] + r[1][0]) / q
+		y = q / 4
+		z = (r[1][2] + r[2][1]) / q
+	default:
+		q := math.Sqrt(1+r[2][2]-r[0][0]-r[1][1]) * 2
+		w = (r[1][0] - r[0][1]) / q
+		x = (r[0][2] + r[2][0]) / q
+		y = (r[1][2] + r[2][1]) / q
+		z = q / 4
+	}
+
+	rotation = Quaternion{Vector3: Vector3{X: float32(x), Y: float32(y), Z: float32(z)}, W: float32(w)}
+	return
+}
+
+// Multiply returns m * other
+func (m *Matrix4x4) Multiply(other *Matrix4x4) Matrix4x4 {
+	var out Matrix4x4
+	for r := range 4 {
+		for c := range 4 {
+			var sum float32
+			for k := range 4 {
+				sum += m[r][k] * other[k][c]
+			}
+			out[r][c] = sum
+		}
+	}
+	return out
+}
+
+// Identity returns the identity matrix
+func Identity() Matrix4x4 {
+	return Matrix4x4{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}}
+}
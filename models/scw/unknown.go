@@ -0,0 +1,23 @@
+package scw
+
+// UnknownChunk is a chunk with a tag this package can not decode, it is kept as is
+// so files from newer versions can be saved back without losing it
+type UnknownChunk struct {
+	Tag  string
+	Body []byte // the raw body, base64 in JSON
+	// Position of the chunk among all the chunks of the File, 0 being HEAD
+	Position int
+}
+
+// unknownProperty encodes an UnknownChunk back, its Tag field hides the Tag method of Sc3dProperty
+type unknownProperty struct {
+	chunk *UnknownChunk
+}
+
+func (u unknownProperty) Tag() string {
+	return u.chunk.Tag
+}
+
+func (u unknownProperty) Encode(writer *Writer) {
+	writer.WriteBytes(u.chunk.Body)
+}
@@ -0,0 +1,124 @@
+package scw
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestUnknownChunks(t *testing.T) {
+	data := corpus(t)["unknown_chunk.scw"]
+	file := load(t, data, LoadOptions{})
+
+	if len(file.UnknownChunks) == 0 {
+		t.Fatal("no unknown chunk loaded")
+	}
+
+	for _, chunk := range file.UnknownChunks {
+		if chunk.Position >= len(file.ChunkOrder) || file.ChunkOrder[chunk.Position] != chunk.Tag {
+			t.Fatalf("unknown chunk %s at position %d of %v", chunk.Tag, chunk.Position, file.ChunkOrder)
+		}
+	}
+
+	// bodies are base64 in JSON
+	encoded := marshal(t, file)
+	body := base64.StdEncoding.EncodeToString(file.UnknownChunks[0].Body)
+	if !bytes.Contains(encoded, []byte(`"Body":"`+body+`"`)) {
+		t.Fatalf("JSON does not hold the base64 body %s:\n%s", body, encoded)
+	}
+
+	fromJSON := New(encoded)
+	if err := fromJSON.LoadJSON(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := fromJSON.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatal("file encoded from JSON differs from the loaded one")
+	}
+}
+
+func TestUnknownChunksPosition(t *testing.T) {
+	file := load(t, corpus(t)["unknown_chunk.scw"], LoadOptions{})
+	file.ChunkOrder = nil
+
+	// without a chunk order unknown chunks are still written at their position
+	tags := chunkTags(file)
+	for _, chunk := range file.UnknownChunks {
+		if tags[chunk.Position] != chunk.Tag {
+			t.Fatalf("unknown chunk %s written at position %d of %v", chunk.Tag, chunk.Position, tags)
+		}
+	}
+
+	if tags[len(tags)-1] != "WEND" {
+		t.Fatalf("chunks %v do not end with WEND", tags)
+	}
+}
+
+func TestUnknownChunksJSONOnly(t *testing.T) {
+	// the JSON of a File without a chunk order, an unknown chunk between HEAD and WEND
+	data, err := json.Marshal(map[string]any{
+		"Version":       2,
+		"UnknownChunks": []UnknownChunk{{Tag: "TEST", Body: []byte{1, 2, 3}, Position: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := New(data)
+	if err = file.LoadJSON(); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := file.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := load(t, encoded, LoadOptions{VerifyCRC: true})
+	if len(loaded.UnknownChunks) != 1 || !bytes.Equal(loaded.UnknownChunks[0].Body, []byte{1, 2, 3}) || loaded.ChunkOrder[1] != "TEST" {
+		t.Fatalf("unknown chunks %+v in chunks %v", loaded.UnknownChunks, loaded.ChunkOrder)
+	}
+}
+
+func TestDecoderUnknownChunks(t *testing.T) {
+	file := New(nil)
+	file.Version = 2
+	for i := range 5 {
+		file.UnknownChunks = append(file.UnknownChunks, UnknownChunk{Tag: "TEST", Body: []byte{byte(i)}, Position: i + 1})
+	}
+
+	data, err := file.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the chunks returned by Decode stay valid while UnknownChunks grows
+	var decoded []UnknownChunk
+	decoder := NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err = decoder.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		prop, err := decoder.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk, ok := prop.(UnknownChunk); ok {
+			decoded = append(decoded, chunk)
+		}
+	}
+
+	if !reflect.DeepEqual(decoded, file.UnknownChunks) || !reflect.DeepEqual(decoder.File().UnknownChunks, file.UnknownChunks) {
+		t.Fatalf("decoded unknown chunks %+v, expected %+v", decoded, file.UnknownChunks)
+	}
+}
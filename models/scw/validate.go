@@ -0,0 +1,176 @@
+package scw
+
+import (
+	"fmt"
+	"io"
+)
+
+// ValidationError is a problem found by Validate, Field is a path like the one of DecodeError
+type ValidationError struct {
+	Field string
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks what decoding can not: references between chunks, indices out of their sources,
+// skin joints and weights, samples overflowing their scale and that the File can be encoded
+func (f *File) Validate() []error {
+	var errs []error
+	fail := func(field string, format string, args ...any) {
+		errs = append(errs, &ValidationError{Field: field, Err: fmt.Errorf(format, args...)})
+	}
+
+	materials := make(map[string]bool)
+	for _, mat := range f.Materials {
+		materials[mat.Name] = true
+	}
+
+	cameras := make(map[string]bool)
+	for _, cam := range f.Cameras {
+		cameras[cam.Name] = true
+	}
+
+	geometries := make(map[string]*Geometry)
+	for _, geom := range f.Geometries {
+		geometries[geom.Name] = geom
+	}
+
+	nodes := make(map[string]bool)
+	for i := range f.Nodes {
+		nodes[f.Nodes[i].Name] = true
+	}
+
+	// joints may be raw nodes (see ErrLightInstance), which can not be checked
+	rawNodes := f.RawNodes != nil
+
+	for i, geom := range f.Geometries {
+		field := fmt.Sprintf("GEOM[%d]", i)
+
+		for j := range geom.Materials {
+			validateIndices(fmt.Sprintf("%s.Materials[%d]", field, j), geom, &geom.Materials[j], fail)
+		}
+
+		if len(geom.Skins.Joints) != len(geom.Skins.InverseBindMatrices) {
+			fail(field+".Skins", "%d joints for %d inverse bind matrices", len(geom.Skins.Joints), len(geom.Skins.InverseBindMatrices))
+		}
+
+		for j, joint := range geom.Skins.Joints {
+			if !nodes[joint] && !rawNodes {
+				fail(fmt.Sprintf("%s.Skins.Joints[%d]", field, j), "unknown node %s", joint)
+			}
+		}
+
+		for j, weight := range geom.SkinWeights {
+			for k, joint := range weight.Joints {
+				if weight.Weights[k] != 0 && int(joint) >= len(geom.Skins.Joints) {
+					fail(fmt.Sprintf("%s.SkinWeights[%d].Joints[%d]", field, j, k), "joint %d out of the %d skin joints", joint, len(geom.Skins.Joints))
+					break
+				}
+			}
+		}
+
+		for j := range geom.Vertices {
+			if geom.Vertices[j].Stride == 0 {
+				fail(fmt.Sprintf("%s.Vertices[%d].Stride", field, j), "%w: 0", ErrSourceStride)
+			} else if len(geom.Vertices[j].Data)%int(geom.Vertices[j].Stride) != 0 {
+				fail(fmt.Sprintf("%s.Vertices[%d].Data", field, j), "%w: %d values for a stride of %d", ErrSourceStride, len(geom.Vertices[j].Data), geom.Vertices[j].Stride)
+			}
+
+			if _, err := geom.Vertices[j].Quantize(geom.Vertices[j].EncodeScale()); err != nil {
+				fail(fmt.Sprintf("%s.Vertices[%d]", field, j), "%w", err)
+			}
+		}
+	}
+
+	for i := range f.Nodes {
+		node := &f.Nodes[i]
+		field := fmt.Sprintf("NODE[%d]", i)
+
+		if len(node.ParentName) != 0 && !nodes[node.ParentName] {
+			fail(field+".ParentName", "unknown node %s", node.ParentName)
+		}
+
+		for j, instance := range node.Instances {
+			field := fmt.Sprintf("%s.Instances[%d]", field, j)
+
+			switch instance.Type {
+			case "GEOM", "CONT":
+				if geometries[instance.Target] == nil {
+					fail(field+".Target", "unknown geometry %s", instance.Target)
+				}
+				for k, mat := range instance.Materials {
+					if !materials[mat.Target] {
+						fail(fmt.Sprintf("%s.Materials[%d].Target", field, k), "unknown material %s", mat.Target)
+					}
+				}
+			case "CAME":
+				if !cameras[instance.Target] {
+					fail(field+".Target", "unknown camera %s", instance.Target)
+				}
+				if len(instance.CameraTarget) != 0 && !nodes[instance.CameraTarget] {
+					fail(field+".CameraTarget", "unknown node %s", instance.CameraTarget)
+				}
+			default:
+				fail(field+".Type", "%w: %s", ErrUnsupportedInstance, instance.Type)
+			}
+		}
+	}
+
+	// only reported when nothing else was found, the reason is most likely one of the above
+	if len(errs) == 0 {
+		if err := f.EncodeTo(io.Discard); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// validateIndices checks that the indices of an IndexArray are in the sources of their input
+func validateIndices(field string, geom *Geometry, indices *IndexArray, fail func(field string, format string, args ...any)) {
+	inputs := int(indices.InputsCount)
+	if inputs == 0 {
+		return
+	}
+
+	if len(indices.IndexBuffer) != 3*int(indices.TrianglesCount)*inputs {
+		fail(field+".IndexBuffer", "%d indices for %d triangles of %d inputs", len(indices.IndexBuffer), indices.TrianglesCount, inputs)
+		return
+	}
+
+	// the smallest count of the sources of every input
+	counts := make([]int, inputs)
+	for i := range counts {
+		counts[i] = -1
+	}
+	for _, src := range geom.Vertices {
+		if int(src.Index) >= inputs || src.Stride == 0 {
+			continue
+		}
+		count := len(src.Data) / int(src.Stride)
+		if counts[src.Index] == -1 || count < counts[src.Index] {
+			counts[src.Index] = count
+		}
+	}
+
+	for input, count := range counts {
+		if count == -1 {
+			fail(field+".InputsCount", "no source for input %d", input)
+			return
+		}
+	}
+
+	for i, idx := range indices.IndexBuffer {
+		if count := counts[i%inputs]; int(idx) >= count {
+			fail(fmt.Sprintf("%s.IndexBuffer[%d]", field, i), "index %d out of the %d values of input %d", idx, count, i%inputs)
+			return
+		}
+	}
+}
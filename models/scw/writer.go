@@ -3,31 +3,69 @@ package scw
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
 	"math"
 )
 
+// Writer writes big endian values, the first write error is kept and every later write is skipped
 type Writer struct {
-	bytes.Buffer
+	w   io.Writer
+	buf *bytes.Buffer // nil for writers created with NewStreamWriter
+	err error
 }
 
+// NewWriter returns a Writer to an in memory buffer, see Bytes
 func NewWriter() *Writer {
-	return &Writer{}
+	buf := new(bytes.Buffer)
+	return &Writer{w: buf, buf: buf}
+}
+
+// NewStreamWriter returns a Writer to w
+func NewStreamWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Err returns the first write error
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Bytes returns the written data of a Writer created with NewWriter
+func (w *Writer) Bytes() []byte {
+	if w.buf == nil {
+		return nil
+	}
+	return w.buf.Bytes()
+}
+
+// Fail sets the Writer error if it has none, for values that can not be encoded
+func (w *Writer) Fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *Writer) write(data []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(data)
 }
 
 func (w *Writer) WriteU8(b byte) {
-	_ = w.WriteByte(b)
+	w.write([]byte{b})
 }
 
 func (w *Writer) WriteU16(value uint16) {
 	var data [2]byte
 	binary.BigEndian.PutUint16(data[:], value)
-	_, _ = w.Write(data[:])
+	w.write(data[:])
 }
 
 func (w *Writer) WriteU32(value uint32) {
 	var data [4]byte
 	binary.BigEndian.PutUint32(data[:], value)
-	_, _ = w.Write(data[:])
+	w.write(data[:])
 }
 
 func (w *Writer) WriteBool(value bool) {
@@ -53,9 +91,9 @@ func (w *Writer) WriteStringUTF(str string) {
 }
 
 func (w *Writer) WriteStringChars(str string) {
-	_, _ = w.WriteString(str)
+	w.write([]byte(str))
 }
 
 func (w *Writer) WriteBytes(bytes []byte) {
-	_, _ = w.Write(bytes)
+	w.write(bytes)
 }